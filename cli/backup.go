@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"wrong.wang/x/go-isso/config"
+	"wrong.wang/x/go-isso/database"
+	"wrong.wang/x/go-isso/logger"
+)
+
+func backupTo(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf("Usage of backup:\n")
+		fmt.Printf("\tgo-isso -c <CONFIG PATH> backup <DEST>\n\n")
+		fmt.Printf("copy the database to DEST while go-isso is running, as a SQLite file for SQLite,\n")
+		fmt.Printf("or as SQL written by pg_dump or mysqldump for PostgreSQL and MySQL.\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	storage := openDatabase(cfg, 5*time.Second)
+	defer storage.Close()
+
+	dest := fs.Arg(0)
+	if err := storage.Backup(context.Background(), dest); err != nil {
+		logger.Fatal("backup database to %s failed: %v", dest, err)
+	}
+	logger.Info("backup database to %s", dest)
+}
+
+// scheduleSnapshots write a snapshot to the backup directory every interval until ctx is done.
+func scheduleSnapshots(ctx context.Context, storage *database.Database, cfg config.Backup) {
+	if cfg.Interval <= 0 || cfg.Directory == "" {
+		return
+	}
+	logger.Info("snapshot database to %s every %s, keep %d", cfg.Directory, cfg.Interval, cfg.Keep)
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			path, err := storage.Snapshot(ctx, cfg.Directory, cfg.Keep)
+			if err != nil {
+				logger.Error("snapshot database failed: %v", err)
+				continue
+			}
+			logger.Info("snapshot database to %s", path)
+		}
+	}
+}
@@ -20,7 +20,7 @@ func Parse() {
 	)
 	flag.Usage = func() {
 		fmt.Printf("Usage of %s:\n", os.Args[0])
-		fmt.Printf("\tgo-isso [-v] -c <CONFIG PATH> [import|run] \n\n")
+		fmt.Printf("\tgo-isso [-v] -c <CONFIG PATH> [import <FORMAT> <PATH>|export [FORMAT]|migrate [status|up]|migrate-storage -to <DSN>|backup <DEST>|db [check|vacuum|repair]|rerender [-all]|run] \n\n")
 		flag.PrintDefaults()
 	}
 
@@ -63,15 +63,27 @@ func Parse() {
 		defer logFile.Close()
 	}
 
-	if flag.NArg() != 1 {
-		fmt.Printf("need one and only one argument to spectify action.\n\n")
+	if flag.NArg() < 1 {
+		fmt.Printf("need one argument to spectify action.\n\n")
 		flag.Usage()
 		return
 	}
 
 	switch action := flag.Arg(0); action {
 	case "import":
-		importFrom()
+		importFrom(*cfg, flag.Args()[1:])
+	case "export":
+		exportTo(*cfg, flag.Args()[1:])
+	case "migrate":
+		migrateSchema(*cfg, flag.Args()[1:])
+	case "migrate-storage":
+		migrateStorage(*cfg, flag.Args()[1:])
+	case "backup":
+		backupTo(*cfg, flag.Args()[1:])
+	case "db":
+		maintainDatabase(*cfg, flag.Args()[1:])
+	case "rerender":
+		rerenderComments(*cfg, flag.Args()[1:])
 	case "run":
 		startDaemon(*cfg)
 	default:
@@ -25,7 +25,14 @@ func startDaemon(cfg config.Config) {
 
 	var httpServer *http.Server
 
-	httpServer = server.Serve(cfg)
+	storage := openDatabase(cfg, 1*time.Second)
+	defer storage.Close()
+
+	snapshotCtx, stopSnapshots := context.WithCancel(context.Background())
+	defer stopSnapshots()
+	go scheduleSnapshots(snapshotCtx, storage, cfg.Backup)
+
+	httpServer = server.Serve(cfg, storage)
 
 	<-stop
 	logger.Info("Shutting down the process...")
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"wrong.wang/x/go-isso/config"
+	"wrong.wang/x/go-isso/database"
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/logger"
+)
+
+func maintainDatabase(cfg config.Config, args []string) {
+	var yes bool
+	fs := flag.NewFlagSet("db", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf("Usage of db:\n")
+		fmt.Printf("\tgo-isso -c <CONFIG PATH> db [check|vacuum|repair] [OPTIONS]\n\n")
+		fmt.Printf("\tcheck\tcheck the integrity and consistency of the database (default)\n")
+		fmt.Printf("\tvacuum\treclaim the space of removed rows\n")
+		fmt.Printf("\trepair\tfix inconsistencies found by check, back up the database first\n\n")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&yes, "y", false, "repair: fix every inconsistency without asking")
+
+	action := "check"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+
+	storage := openDatabase(cfg, 5*time.Second)
+	defer storage.Close()
+
+	ctx := context.Background()
+	switch action {
+	case "check":
+		if !checkDatabase(ctx, storage) {
+			os.Exit(1)
+		}
+	case "vacuum":
+		start := time.Now()
+		if err := storage.Vacuum(ctx); err != nil {
+			logger.Fatal("vacuum database failed: %v", err)
+		}
+		logger.Info("vacuum database in %s", time.Since(start))
+	case "repair":
+		repairDatabase(ctx, storage, yes)
+	default:
+		fmt.Printf("%s is not supported db action\n\n", action)
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+// checkDatabase print problems of storage and report whether it is healthy.
+func checkDatabase(ctx context.Context, storage *database.Database) bool {
+	healthy := true
+	problems, err := storage.IntegrityCheck(ctx)
+	switch {
+	case errors.Is(err, isso.ErrNotSupported):
+		fmt.Printf("integrity: skipped, %v\n", err)
+	case err != nil:
+		logger.Fatal("check integrity failed: %v", err)
+	case len(problems) == 0:
+		fmt.Printf("integrity: ok\n")
+	default:
+		healthy = false
+		for _, problem := range problems {
+			fmt.Printf("integrity: %s\n", problem)
+		}
+	}
+
+	found, err := storage.CheckConsistency(ctx)
+	if err != nil {
+		logger.Fatal("check consistency failed: %v", err)
+	}
+	if len(found) == 0 {
+		fmt.Printf("consistency: ok\n")
+	}
+	for _, inconsistency := range found {
+		healthy = false
+		printInconsistency(inconsistency)
+	}
+	return healthy
+}
+
+func printInconsistency(inconsistency database.Inconsistency) {
+	ids := fmt.Sprint(inconsistency.IDs)
+	if len(inconsistency.IDs) > 10 {
+		ids = fmt.Sprint(inconsistency.IDs[:10]) + "..."
+	}
+	fmt.Printf("consistency: %d %s, id %s\n", len(inconsistency.IDs), inconsistency.Description, ids)
+}
+
+// repairDatabase fix inconsistencies one kind after another, until none is left or the rest is declined.
+func repairDatabase(ctx context.Context, storage *database.Database, yes bool) {
+	declined := map[string]bool{}
+	stdin := bufio.NewReader(os.Stdin)
+	for {
+		found, err := storage.CheckConsistency(ctx)
+		if err != nil {
+			logger.Fatal("check consistency failed: %v", err)
+		}
+		var next *database.Inconsistency
+		for i := range found {
+			if !declined[found[i].Kind] {
+				next = &found[i]
+				break
+			}
+		}
+		if next == nil {
+			logger.Info("repair done, %d kinds of inconsistency left", len(found))
+			return
+		}
+
+		printInconsistency(*next)
+		if !yes {
+			fmt.Printf("%s? [y/N] ", next.Fix)
+			answer, _ := stdin.ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				declined[next.Kind] = true
+				continue
+			}
+		}
+		n, err := storage.Repair(ctx, next.Kind)
+		if err != nil {
+			logger.Fatal("repair %s failed: %v", next.Kind, err)
+		}
+		logger.Info("repaired %d %s", n, next.Description)
+	}
+}
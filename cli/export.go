@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"wrong.wang/x/go-isso/config"
+	"wrong.wang/x/go-isso/exporter"
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/logger"
+)
+
+func exportTo(cfg config.Config, args []string) {
+	format := "json"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		format, args = args[0], args[1:]
+	}
+
+	var out string
+	var withHTML bool
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf("Usage of export:\n")
+		fmt.Printf("\tgo-isso -c <CONFIG PATH> export [FORMAT] [OPTIONS]\n\n")
+		fmt.Printf("Supported formats:\n")
+		fmt.Printf("\tjson\tnewline delimited JSON, can be imported with `import json` (default)\n")
+		fmt.Printf("\tstatic\tpublic rendered comments, one JSON file per thread, needs -out\n\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&out, "out", "", "write to file instead of standard output, or the directory for static")
+	fs.BoolVar(&withHTML, "html", false, "static: also write a HTML fragment per thread")
+	fs.Parse(args)
+
+	storage := openDatabase(cfg, 5*time.Second)
+	defer storage.Close()
+
+	var err error
+	ctx := context.Background()
+	switch format {
+	case "json":
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.Create(out)
+			if err != nil {
+				logger.Fatal("create %s failed: %v", out, err)
+			}
+			defer f.Close()
+			w = f
+		}
+		err = exporter.JSON(ctx, storage, w)
+	case "static":
+		if out == "" {
+			fs.Usage()
+			os.Exit(2)
+		}
+		var index []exporter.StaticThread
+		index, err = exporter.Static(ctx, storage, isso.NewRenderer(cfg, storage), exporter.StaticOptions{Out: out, HTML: withHTML})
+		if err == nil {
+			logger.Info("exported %d threads to %s", len(index), out)
+		}
+	default:
+		fmt.Printf("%s is not supported export format\n\n", format)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Fatal("%v", err)
+	}
+}
@@ -1,9 +1,70 @@
 package cli
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"wrong.wang/x/go-isso/config"
+	"wrong.wang/x/go-isso/importer"
 	"wrong.wang/x/go-isso/logger"
 )
 
-func importFrom() {
-	logger.Error("work in process")
+func importFrom(cfg config.Config, args []string) {
+	var opts importer.Options
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf("Usage of import:\n")
+		fmt.Printf("\tgo-isso -c <CONFIG PATH> import [OPTIONS] <FORMAT> <PATH>\n\n")
+		fmt.Printf("Supported formats:\n")
+		fmt.Printf("\tisso\tSQLite database of the python isso\n")
+		fmt.Printf("\tdisqus\tDisqus XML export\n")
+		fmt.Printf("\twordpress\tWordPress eXtended RSS (WXR) export\n")
+		fmt.Printf("\tjson\tdocument written by `go-isso export json`\n\n")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "only read and count the source, write nothing")
+	fs.BoolVar(&opts.Rewrite.KeepDomain, "keep-domain", false, "keep scheme and host in thread uri")
+	fs.StringVar(&opts.Rewrite.OldPrefix, "old-prefix", "", "path prefix of exported links to be replaced")
+	fs.StringVar(&opts.Rewrite.NewPrefix, "new-prefix", "", "path prefix to replace `old-prefix` with")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	format, path := fs.Arg(0), fs.Arg(1)
+
+	storage := openDatabase(cfg, 5*time.Second)
+	defer storage.Close()
+
+	var (
+		result importer.Result
+		err    error
+	)
+	ctx := context.Background()
+	switch format {
+	case "isso":
+		result, err = importer.FromISSO(ctx, storage, path, opts)
+	case "disqus":
+		result, err = importer.FromDisqus(ctx, storage, path, opts)
+	case "wordpress":
+		result, err = importer.FromWordPress(ctx, storage, path, opts)
+	case "json":
+		result, err = importer.FromJSON(ctx, storage, path, opts)
+	default:
+		fmt.Printf("%s is not supported import format\n\n", format)
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		logger.Fatal("import from %s failed after %s: %v", path, result, err)
+	}
+	if opts.DryRun {
+		logger.Info("dry run, would import %s", result)
+		return
+	}
+	logger.Info("imported %s", result)
 }
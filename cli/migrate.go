@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"wrong.wang/x/go-isso/config"
+	"wrong.wang/x/go-isso/database"
+	"wrong.wang/x/go-isso/logger"
+)
+
+// openDatabase open the database of cfg, migrate it if auto-migrate is enabled,
+// exit if the schema is not the latest one.
+func openDatabase(cfg config.Config, timeout time.Duration) *database.Database {
+	storage, err := database.OpenWithOptions(cfg.DBPath, timeout, databaseOptions(cfg))
+	if err != nil {
+		logger.Fatal("init database failed %v", err)
+	}
+	storage.ExactVotes = cfg.ExactVotes
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	status, err := storage.SchemaStatus(ctx)
+	if err != nil {
+		logger.Fatal("read database schema version failed %v", err)
+	}
+	if len(status.Pending) == 0 {
+		return storage
+	}
+	if !cfg.AutoMigrate {
+		logger.Fatal("%v: version %d, want %d", database.ErrSchemaOutdated, status.Current, status.Latest)
+	}
+	if _, err := storage.Migrate(ctx); err != nil {
+		logger.Fatal("migrate database failed %v", err)
+	}
+	return storage
+}
+
+func databaseOptions(cfg config.Config) database.Options {
+	return database.Options{
+		JournalMode:     cfg.Database.JournalMode,
+		BusyTimeout:     cfg.Database.BusyTimeout,
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+	}
+}
+
+func migrateSchema(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf("Usage of migrate:\n")
+		fmt.Printf("\tgo-isso -c <CONFIG PATH> migrate [status|up]\n\n")
+		fmt.Printf("\tstatus\tshow the schema version and pending migrations (default)\n")
+		fmt.Printf("\tup\tapply all pending migrations\n")
+	}
+	fs.Parse(args)
+
+	action := "status"
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+	}
+
+	storage, err := database.OpenWithOptions(cfg.DBPath, 30*time.Second, databaseOptions(cfg))
+	if err != nil {
+		if errors.Is(err, database.ErrSchemaTooNew) {
+			logger.Fatal("%v, please upgrade go-isso", err)
+		}
+		logger.Fatal("init database failed %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	switch action {
+	case "status":
+		status, err := storage.SchemaStatus(ctx)
+		if err != nil {
+			logger.Fatal("read database schema version failed %v", err)
+		}
+		fmt.Printf("schema version %d, latest %d\n", status.Current, status.Latest)
+		for i, description := range status.Pending {
+			fmt.Printf("pending %d: %s\n", status.Current+i+1, description)
+		}
+	case "up":
+		n, err := storage.Migrate(ctx)
+		if err != nil {
+			logger.Fatal("migrate database failed %v", err)
+		}
+		logger.Info("applied %d migrations, schema version %d", n, database.LatestSchemaVersion)
+	default:
+		fmt.Printf("%s is not supported migrate action\n\n", action)
+		fs.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"wrong.wang/x/go-isso/config"
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/logger"
+)
+
+func rerenderComments(cfg config.Config, args []string) {
+	var all bool
+	fs := flag.NewFlagSet("rerender", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Printf("Usage of rerender:\n")
+		fmt.Printf("\tgo-isso -c <CONFIG PATH> rerender [-all]\n\n")
+		fmt.Printf("render comments again and save the html, e.g. after [markup] is changed.\n")
+		fmt.Printf("Otherwise comments are rendered again when they are fetched.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&all, "all", false, "render every comment, not only those with stale html")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	storage := openDatabase(cfg, 5*time.Second)
+	defer storage.Close()
+
+	start := time.Now()
+	n, err := isso.New(cfg, storage).Rerender(context.Background(), all)
+	if err != nil {
+		logger.Fatal("rerender comments failed after %d comments: %v", n, err)
+	}
+	logger.Info("rerender %d comments in %s", n, time.Since(start))
+}
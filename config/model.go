@@ -1,9 +1,14 @@
 package config
 
+import "time"
+
 // Config is the main config struct for go-isso
 // All go-isso config related with it.
 type Config struct {
 	DBPath             string   `ini:"dbpath"`
+	AutoMigrate        bool     `ini:"auto-migrate"`
+	ExactVotes         bool     `ini:"exact-votes"`
+	Reactions          []string `ini:"reactions"`
 	Name               string   `ini:"name"` // required to dispatch multiple websites, not used otherwise.
 	Host               []string `ini:"host"`
 	MaxAge             int
@@ -13,6 +18,8 @@ type Config struct {
 	Gravatar           bool     `ini:"gravatar"`
 	GravatarURL        string   `ini:"gravatar-url"`
 	Server             Server
+	Database           Database
+	Backup             Backup
 	Admin              Admin
 	Moderation         Moderation
 	SMTP               SMTP
@@ -25,6 +32,22 @@ type Server struct {
 	Guard          Guard
 }
 
+// Database tune the database connections
+type Database struct {
+	JournalMode     string        `ini:"journal-mode"`
+	BusyTimeout     time.Duration `ini:"busy-timeout"`
+	MaxOpenConns    int           `ini:"max-open-conns"`
+	MaxIdleConns    int           `ini:"max-idle-conns"`
+	ConnMaxLifetime time.Duration `ini:"conn-max-lifetime"`
+}
+
+// Backup config of scheduled snapshots
+type Backup struct {
+	Directory string        `ini:"directory"`
+	Interval  time.Duration `ini:"interval"`
+	Keep      int           `ini:"keep"`
+}
+
 // Admin interface config
 type Admin struct {
 	Password string `ini:"password"`
@@ -51,8 +74,19 @@ type Guard struct {
 
 // Markup store basic Customize markup and sanitized HTML config
 type Markup struct {
+	Language          string   `ini:"language"`
+	Extensions        []string `ini:"extensions"`
 	AllowedElements   []string `ini:"allowed-elements"`
 	AllowedAttributes []string `ini:"allowed-attributes"`
+
+	Highlight          bool     `ini:"highlight"`
+	HighlightLanguages []string `ini:"highlight-languages"`
+	HighlightMaxSize   int      `ini:"highlight-max-size"`
+
+	LinkTargetBlank         bool     `ini:"link-target-blank"`
+	LinkAllowedDomains      []string `ini:"link-allowed-domains"`
+	LinkBlockedDomains      []string `ini:"link-blocked-domains"`
+	UnapprovedBareLinksText bool     `ini:"unapproved-bare-links-as-text"`
 }
 
 // SMTP save notify thought smtp config
@@ -28,6 +28,14 @@ func Parse(configPath string) (*Config, error) {
 
 	splitStringtoStrings(&mc.Host, "\n")
 	splitStringtoStrings(&mc.Notify, ",")
+	err = INIConfig.Section("database").MapTo(&mc.Database)
+	if err != nil {
+		return nil, err
+	}
+	err = INIConfig.Section("backup").MapTo(&mc.Backup)
+	if err != nil {
+		return nil, err
+	}
 	err = INIConfig.Section("admin").MapTo(&mc.Admin)
 	if err != nil {
 		return nil, err
@@ -48,8 +56,12 @@ func Parse(configPath string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	splitStringtoStrings(&mc.Server.Guard.Markup.Extensions, ",")
 	splitStringtoStrings(&mc.Server.Guard.Markup.AllowedElements, ",")
 	splitStringtoStrings(&mc.Server.Guard.Markup.AllowedAttributes, ",")
+	splitStringtoStrings(&mc.Server.Guard.Markup.HighlightLanguages, ",")
+	splitStringtoStrings(&mc.Server.Guard.Markup.LinkAllowedDomains, ",")
+	splitStringtoStrings(&mc.Server.Guard.Markup.LinkBlockedDomains, ",")
 	err = INIConfig.Section("smtp").MapTo(&mc.SMTP)
 	if err != nil {
 		return nil, err
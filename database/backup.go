@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"wrong.wang/x/go-isso/isso"
+)
+
+// snapshotPrefix and snapshotTime name the files written by Snapshot,
+// names of snapshots sort as their time.
+const (
+	snapshotPrefix = "comments-"
+	snapshotTime   = "20060102T150405.000Z"
+)
+
+// Backup write a copy of the database to dest while it is in use, dest is replaced only if it succeeds.
+// SQLite is copied with the online backup API into a SQLite file,
+// PostgreSQL and MySQL are dumped as SQL by pg_dump and mysqldump, which must be in PATH.
+func (d *Database) Backup(ctx context.Context, dest string) error {
+	tmp := dest + ".tmp"
+	os.Remove(tmp)
+
+	var err error
+	switch d.driver {
+	case "sqlite3":
+		err = d.backupSQLite(ctx, tmp)
+	default:
+		err = d.dump(ctx, tmp)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return wraperror(err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return wraperror(err)
+	}
+	return nil
+}
+
+// BackupExt is the file extension of what Backup writes, .db for SQLite and .sql for dumps.
+func (d *Database) BackupExt() string {
+	if d.driver == "sqlite3" {
+		return ".db"
+	}
+	return ".sql"
+}
+
+// backupSQLite copy the database with connections of its own,
+// so the pool of database/sql keeps serving while the pages are copied.
+func (d *Database) backupSQLite(ctx context.Context, dest string) error {
+	if strings.Contains(d.dsn, ":memory:") || strings.Contains(d.dsn, "mode=memory") {
+		return fmt.Errorf("%w: backup of in-memory SQLite database", isso.ErrNotSupported)
+	}
+	driver := &sqlite3.SQLiteDriver{}
+	src, err := driver.Open(d.dsn)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := driver.Open(dest)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	backup, err := dst.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+	if err != nil {
+		return err
+	}
+	// Copy all pages in one step, it reads a snapshot and does not block writers in WAL mode.
+	// Copying in small steps would restart whenever a comment is written in between.
+	for {
+		done, err := backup.Step(-1)
+		if err != nil {
+			backup.Finish()
+			return err
+		}
+		if done {
+			return backup.Finish()
+		}
+		// the source is locked by a writer without WAL, try again
+		select {
+		case <-ctx.Done():
+			backup.Finish()
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// dump run pg_dump or mysqldump to write the database to dest as SQL statements.
+func (d *Database) dump(ctx context.Context, dest string) error {
+	var cmd *exec.Cmd
+	switch d.driver {
+	case "postgres":
+		dsn, password, err := splitPassword(d.dsn)
+		if err != nil {
+			return err
+		}
+		cmd = exec.CommandContext(ctx, "pg_dump", "--no-owner", "--dbname="+dsn, "--file="+dest)
+		// keep the password out of the process list
+		if password != "" {
+			cmd.Env = append(os.Environ(), "PGPASSWORD="+password)
+		}
+	case "mysql":
+		cfg, err := mysql.ParseDSN(d.dsn)
+		if err != nil {
+			return err
+		}
+		args := []string{"--single-transaction", "--result-file=" + dest, "--user=" + cfg.User}
+		if cfg.Net == "unix" {
+			args = append(args, "--socket="+cfg.Addr)
+		} else if host, port, err := net.SplitHostPort(cfg.Addr); err == nil {
+			args = append(args, "--host="+host, "--port="+port, "--protocol=tcp")
+		}
+		cmd = exec.CommandContext(ctx, "mysqldump", append(args, cfg.DBName)...)
+		// keep the password out of the process list
+		cmd.Env = append(os.Environ(), "MYSQL_PWD="+cfg.Passwd)
+	default:
+		return fmt.Errorf("%w: backup of %s", isso.ErrNotSupported, d.driver)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.Path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// splitPassword remove the password from a postgres:// dsn, from its user info
+// or its password parameter, and return it apart.
+func splitPassword(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", err
+	}
+	var password string
+	if u.User != nil {
+		password, _ = u.User.Password()
+		u.User = url.User(u.User.Username())
+	}
+	if query := u.Query(); query.Get("password") != "" {
+		password = query.Get("password")
+		query.Del("password")
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), password, nil
+}
+
+// Snapshot back up the database to a new file of dir named by the current time,
+// then remove older snapshots in dir but the latest keep ones. keep <= 0 keeps all.
+func (d *Database) Snapshot(ctx context.Context, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", wraperror(err)
+	}
+	dest := filepath.Join(dir, snapshotPrefix+time.Now().UTC().Format(snapshotTime)+d.BackupExt())
+	if err := d.Backup(ctx, dest); err != nil {
+		return "", err
+	}
+	if keep <= 0 {
+		return dest, nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return dest, wraperror(err)
+	}
+	var snapshots []string
+	for _, f := range files {
+		name := f.Name()
+		if f.Mode().IsRegular() && strings.HasPrefix(name, snapshotPrefix) && filepath.Ext(name) == d.BackupExt() {
+			snapshots = append(snapshots, name)
+		}
+	}
+	sort.Strings(snapshots)
+	for i := 0; i < len(snapshots)-keep; i++ {
+		if err := os.Remove(filepath.Join(dir, snapshots[i])); err != nil {
+			return dest, wraperror(err)
+		}
+	}
+	return dest, nil
+}
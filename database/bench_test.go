@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// benchThreads threads with benchComments comments each, every fourth comment is a reply.
+const (
+	benchThreads  = 1000
+	benchComments = 100
+)
+
+// newBenchDatabase create a SQLite file of 100k comments in dir.
+func newBenchDatabase(b *testing.B, dir string, opts Options) *Database {
+	b.Helper()
+	d, err := NewWithOptions(filepath.Join(dir, "comments.db"), 10*time.Second, opts)
+	if err != nil {
+		b.Fatalf("NewWithOptions() error = %v", err)
+	}
+	tx, err := d.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT INTO comments (tid, id, parent, created, mode, remote_addr, text, author, voters)
+		VALUES ($1, $2, $3, $4, 1, $5, 'text', 'author', x'00')`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var id int64
+	for tid := 1; tid <= benchThreads; tid++ {
+		if _, err := tx.Exec(`INSERT INTO threads (id, uri, title) VALUES ($1, $2, 'title')`, tid, fmt.Sprintf("/post/%d/", tid)); err != nil {
+			b.Fatal(err)
+		}
+		var parent *int64
+		for i := 0; i < benchComments; i++ {
+			id++
+			if i%4 == 0 {
+				parent = nil
+			}
+			created := 1500000000 + float64(id)
+			if _, err := stmt.Exec(tid, id, parent, created, fmt.Sprintf("10.%d.%d.%d", id%7, id%256, id%199)); err != nil {
+				b.Fatal(err)
+			}
+			if parent == nil {
+				p := id
+				parent = &p
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+	return d
+}
+
+func dropCommentIndexes(b *testing.B, d *Database) {
+	for _, index := range []string{"comments_tid", "comments_parent", "comments_remote_addr", "comments_created"} {
+		if _, err := d.Exec(`DROP INDEX ` + index); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkIndexes compare the fetch and guard queries of 100k comments with and without indexes, run
+//
+//	go test -run - -bench Indexes ./database
+func BenchmarkIndexes(b *testing.B) {
+	dir, err := ioutil.TempDir("", "go-isso-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	d := newBenchDatabase(b, dir, Options{})
+	defer d.Close()
+
+	ctx := context.Background()
+	run := func(b *testing.B) {
+		b.Run("FetchCommentsByURI", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				uri := fmt.Sprintf("/post/%d/", i%benchThreads+1)
+				if _, err := d.FetchCommentsByURI(ctx, uri, -1, isso.ModePublic, "id", true); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("CountReply", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				uri := fmt.Sprintf("/post/%d/", i%benchThreads+1)
+				if _, err := d.CountReply(ctx, uri, isso.ModePublic, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("NewCommentGuard", func(b *testing.B) {
+			c := isso.Comment{RemoteAddr: "10.1.1.1"}
+			for i := 0; i < b.N; i++ {
+				d.NewCommentGuard(ctx, c, "/post/1/", 1000, 1000, true, 0)
+			}
+		})
+	}
+	b.Run("indexed", run)
+	dropCommentIndexes(b, d)
+	b.Run("unindexed", run)
+}
+
+// BenchmarkConcurrency fetch and comment in parallel on 100k comments, one of eight requests writes.
+// It compares a single connection with rollback journal, as before, to WAL with a pool, run
+//
+//	go test -run - -bench Concurrency -cpu 8 ./database
+func BenchmarkConcurrency(b *testing.B) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"delete-1conn", Options{JournalMode: "DELETE", MaxOpenConns: 1}},
+		{"wal-4conns", Options{MaxOpenConns: 4}},
+		{"wal-8conns", Options{MaxOpenConns: 8}},
+	}
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "go-isso-bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		d := newBenchDatabase(b, dir, tt.opts)
+		defer d.Close()
+
+		b.Run(tt.name, func(b *testing.B) {
+			ctx := context.Background()
+			var ops, failed int64
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewSource(time.Now().UnixNano()))
+				for pb.Next() {
+					tid := r.Int63n(benchThreads) + 1
+					var err error
+					if atomic.AddInt64(&ops, 1)%8 == 0 {
+						_, err = d.NewComment(ctx, isso.Comment{Text: "new", Mode: isso.ModeAccepted}, tid, "10.2.2.2")
+					} else {
+						_, err = d.FetchCommentsByURI(ctx, fmt.Sprintf("/post/%d/", tid), -1, isso.ModePublic, "id", true)
+					}
+					if err != nil {
+						atomic.AddInt64(&failed, 1)
+					}
+				}
+			})
+			b.ReportMetric(float64(failed), "errors")
+		})
+	}
+}
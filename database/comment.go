@@ -3,7 +3,6 @@ package database
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 
 	"gopkg.in/guregu/null.v4"
@@ -20,7 +19,7 @@ func (d *Database) IsApprovedAuthor(ctx context.Context, email string) bool {
 		return false
 	}
 	var flag int64
-	err := d.DB.QueryRowContext(ctx, d.statement["comment_is_previously_approved_author"], email).Scan(&flag)
+	err := d.conn().QueryRowContext(ctx, d.statement["comment_is_previously_approved_author"], email).Scan(&flag)
 	return (err == nil) && (flag == 1)
 }
 
@@ -29,35 +28,33 @@ func (d *Database) NewComment(ctx context.Context, c isso.Comment, threadID int6
 	ctx, cancel := d.withTimeout(ctx)
 	defer cancel()
 	logger.Debug("create %s 's comment at %d", c.Author, threadID)
-	if c.Parent != nil {
-		parent, err := d.getComment(ctx, *c.Parent)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return isso.Comment{}, wraperror(err)
+
+	var comment isso.Comment
+	err := d.withTx(ctx, func(tx *Database) error {
+		if c.Parent != nil {
+			parent, err := tx.getComment(ctx, *c.Parent)
+			if err != nil {
+				return err
+			}
+			if parent.TID != threadID {
+				return isso.ErrInvalidParam
+			}
+			if parent.Parent.Valid {
+				c.Parent = &parent.Parent.Int64
 			}
-			return isso.Comment{}, wraperror(err)
-		}
-		if parent.TID != threadID {
-			return isso.Comment{}, wraperror(err)
-		}
-		if parent.Parent.Valid {
-			c.Parent = &parent.Parent.Int64
 		}
-	}
 
-	nc := newNullComment(c, threadID, remoteAddr)
+		nc := newNullComment(c, threadID, remoteAddr)
 
-	result, err := d.DB.ExecContext(ctx, d.statement["comment_new"], nc.TID, nc.Parent, nc.Created,
-		nc.Modified, nc.Mode, nc.RemoteAddr, nc.Text, nc.Author, nc.Email, nc.Website, nc.Voters, nc.Notification,
-	)
-	if err != nil {
-		return isso.Comment{}, wraperror(err)
-	}
-	id, err := result.LastInsertId()
-	if err != nil {
-		return isso.Comment{}, wraperror(err)
-	}
-	comment, err := d.GetComment(ctx, id)
+		id, err := tx.insert(ctx, tx.statement["comment_new"], nc.TID, nc.Parent, nc.Created,
+			nc.Modified, nc.Mode, nc.RemoteAddr, nc.Text, nc.Author, nc.Email, nc.Website, nc.Voters, nc.Notification,
+		)
+		if err != nil {
+			return err
+		}
+		comment, err = tx.GetComment(ctx, id)
+		return err
+	})
 	if err != nil {
 		return isso.Comment{}, wraperror(err)
 	}
@@ -78,9 +75,15 @@ func (d *Database) getComment(ctx context.Context, id int64) (nullComment, error
 	ctx, cancel := d.withTimeout(ctx)
 	defer cancel()
 
+	// lock the row until the transaction ends, it may be written back
+	stmt := d.statement["comment_get_by_id"]
+	if d.tx != nil {
+		stmt = d.statement["comment_get_by_id_for_update"]
+	}
+
 	var nc nullComment
 	voters := make([]byte, 256)
-	err := d.DB.QueryRowContext(ctx, d.statement["comment_get_by_id"], id).Scan(
+	err := d.conn().QueryRowContext(ctx, stmt, id).Scan(
 		&nc.TID, &nc.ID, &nc.Parent, &nc.Created, &nc.Modified, &nc.Mode,
 		&nc.RemoteAddr, &nc.Text, &nc.Author, &nc.Email, &nc.Website, &nc.Likes,
 		&nc.Dislikes, &voters, &nc.Notification,
@@ -101,7 +104,7 @@ func (d *Database) CountReply(ctx context.Context, uri string, mode int, after f
 
 	counts := map[int64]int64{}
 
-	rows, err := d.DB.QueryContext(ctx, d.statement["comment_count_reply"], uri, mode, mode, after)
+	rows, err := d.conn().QueryContext(ctx, d.statement["comment_count_reply"], uri, mode, mode, after)
 	if err != nil {
 		return nil, wraperror(err)
 	}
@@ -149,19 +152,19 @@ func (d *Database) FetchCommentsByURI(ctx context.Context, uri string, parent in
 	switch {
 	case parent < 0:
 		stmt := d.statement["comment_fetch_by_uri"] + condition
-		rows, err = d.DB.QueryContext(ctx, stmt, uri, mode, mode)
+		rows, err = d.conn().QueryContext(ctx, stmt, uri, mode, mode)
 	case parent == 0:
-		stmt := d.statement["comment_fetch_by_uri"] + ` AND comments.parent IS NULL ` + condition
-		rows, err = d.DB.QueryContext(ctx, stmt, uri, mode, mode)
+		stmt := d.statement["comment_fetch_by_uri"] + d.statement["comment_fetch_by_uri_top"] + condition
+		rows, err = d.conn().QueryContext(ctx, stmt, uri, mode, mode)
 	case parent > 0:
-		stmt := d.statement["comment_fetch_by_uri"] + ` AND comments.parent=? ` + condition
-		rows, err = d.DB.QueryContext(ctx, stmt, uri, mode, mode, parent)
+		stmt := d.statement["comment_fetch_by_uri"] + d.statement["comment_fetch_by_uri_parent"] + condition
+		rows, err = d.conn().QueryContext(ctx, stmt, uri, mode, mode, parent)
 	}
 
-	defer rows.Close()
 	if err != nil {
 		return nil, wraperror(err)
 	}
+	defer rows.Close()
 
 	commentsbyparent := map[int64][]isso.Comment{}
 
@@ -198,12 +201,11 @@ func (d *Database) CountComment(ctx context.Context, uris []string) (map[string]
 	if len(uris) == 0 {
 		return commentByURI, nil
 	}
-	rows, err := d.DB.QueryContext(ctx, d.statement["comment_count"])
-	defer rows.Close()
-
+	rows, err := d.conn().QueryContext(ctx, d.statement["comment_count"])
 	if err != nil {
 		return nil, wraperror(err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var uri string
@@ -276,42 +278,102 @@ func (d *Database) DeleteComment(ctx context.Context, cid int64) (isso.Comment,
 	defer cancel()
 	logger.Debug("delete comment %d", cid)
 
-	var n int64
-	var err error
-	if err = d.DB.QueryRowContext(ctx, d.statement["comment_delete_check"], cid).Scan(&n); err == nil {
-		stmt := d.statement["comment_delete_hard"]
-		if n > 0 {
-			stmt = d.statement["comment_delete_soft"]
+	var comment isso.Comment
+	err := d.withTx(ctx, func(tx *Database) error {
+		var n int64
+		if err := tx.conn().QueryRowContext(ctx, tx.statement["comment_delete_check"], cid).Scan(&n); err != nil {
+			return err
 		}
-		if err = d.execstmt(ctx, nil, nil, stmt, cid); err == nil {
-			if err = d.execstmt(ctx, nil, nil, d.statement["comment_delete_stale"]); err == nil {
-				if n > 0 {
-					return d.GetComment(ctx, cid)
-				}
-				return isso.Comment{}, nil
+		stmt := tx.statement["comment_delete_hard"]
+		if n > 0 {
+			stmt = tx.statement["comment_delete_soft"]
+			// the html of a soft deleted comment would still show its text
+			if err := tx.execstmt(ctx, nil, nil, tx.statement["rendered_delete"], cid); err != nil {
+				return err
 			}
 		}
+		if err := tx.execstmt(ctx, nil, nil, stmt, cid); err != nil {
+			return err
+		}
+		if err := tx.execstmt(ctx, nil, nil, tx.statement["comment_delete_stale"]); err != nil {
+			return err
+		}
+		if err := tx.execstmt(ctx, nil, nil, tx.statement["vote_delete_stale"]); err != nil {
+			return err
+		}
+		if err := tx.execstmt(ctx, nil, nil, tx.statement["reaction_delete_stale"]); err != nil {
+			return err
+		}
+		if err := tx.execstmt(ctx, nil, nil, tx.statement["rendered_delete_stale"]); err != nil {
+			return err
+		}
+		if n > 0 {
+			var err error
+			comment, err = tx.GetComment(ctx, cid)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return isso.Comment{}, wraperror(err)
 	}
-	return isso.Comment{}, wraperror(err)
+	return comment, nil
 }
 
-// VoteComment vote  comment, but if may failed when break limit
-func (d *Database) VoteComment(ctx context.Context, c isso.Comment, up bool) error {
+// RestoreComment save comment as it is, used by importers
+func (d *Database) RestoreComment(ctx context.Context, c isso.Comment, threadID int64) (isso.Comment, error) {
 	ctx, cancel := d.withTimeout(ctx)
 	defer cancel()
-	logger.Debug("vote comment %d", c.ID)
+	logger.Debug("restore comment %d at %d", c.ID, threadID)
 
-	if up {
-		c.Likes++
-	} else {
-		c.Dislikes++
+	id, err := d.insert(ctx, d.statement["comment_restore"],
+		threadID, null.NewInt(c.ID, c.ID != 0), null.IntFromPtr(c.Parent), c.Created,
+		null.FloatFromPtr(c.Modified), c.Mode, c.RemoteAddr, c.Text, c.Author,
+		null.StringFromPtr(c.Email), null.StringFromPtr(c.Website), c.Likes, c.Dislikes,
+		c.Voters[:], c.Notification,
+	)
+	if err != nil {
+		return isso.Comment{}, wraperror(err)
 	}
+	if stmt, ok := d.statement["comment_restore_sequence"]; ok && c.ID != 0 {
+		if err := d.execstmt(ctx, nil, nil, stmt); err != nil {
+			return isso.Comment{}, wraperror(err)
+		}
+	}
+	comment, err := d.GetComment(ctx, id)
+	if err != nil {
+		return isso.Comment{}, wraperror(err)
+	}
+	return comment, nil
+}
 
-	voters := make([]byte, 256)
-	copy(voters, c.Voters[:])
-	err := d.execstmt(ctx, nil, nil, d.statement["comment_vote_set"], c.Likes, c.Dislikes, voters, c.ID)
+// ListComments return all comments of thread
+func (d *Database) ListComments(ctx context.Context, threadID int64) ([]isso.Comment, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("list comments of %d", threadID)
+
+	rows, err := d.conn().QueryContext(ctx, d.statement["comment_list"], threadID)
 	if err != nil {
-		return wraperror(err)
+		return nil, wraperror(err)
 	}
-	return nil
+	defer rows.Close()
+
+	var comments []isso.Comment
+	for rows.Next() {
+		var nc nullComment
+		err := rows.Scan(
+			&nc.TID, &nc.ID, &nc.Parent, &nc.Created, &nc.Modified, &nc.Mode,
+			&nc.RemoteAddr, &nc.Text, &nc.Author, &nc.Email, &nc.Website, &nc.Likes,
+			&nc.Dislikes, &nc.Voters, &nc.Notification,
+		)
+		if err != nil {
+			return nil, wraperror(err)
+		}
+		comments = append(comments, nc.ToComment())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wraperror(err)
+	}
+	return comments, nil
 }
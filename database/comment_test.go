@@ -48,3 +48,120 @@ func TestDatabase_GetComment(t *testing.T) {
 		}
 	})
 }
+
+func TestDatabase_CountComment(t *testing.T) {
+	ctx := context.Background()
+	thread, err := db.NewThread(ctx, "/count", "count")
+	if err != nil {
+		t.Fatalf("Database.NewThread() error = %v", err)
+	}
+	pending, err := db.NewThread(ctx, "/count-pending", "count pending")
+	if err != nil {
+		t.Fatalf("Database.NewThread() error = %v", err)
+	}
+	for _, c := range []struct {
+		tid  int64
+		mode int
+	}{{thread.ID, isso.ModeAccepted}, {thread.ID, isso.ModeModeration}, {pending.ID, isso.ModeModeration}} {
+		if _, err := db.NewComment(ctx, isso.Comment{Text: "count me", Mode: c.mode}, c.tid, "10.0.0.1"); err != nil {
+			t.Fatalf("Database.NewComment() error = %v", err)
+		}
+	}
+
+	// comments not accepted were counted with a NULL uri, which could not be scanned
+	got, err := db.CountComment(ctx, []string{thread.URI, pending.URI, "/count-missing"})
+	if err != nil {
+		t.Fatalf("Database.CountComment() error = %v", err)
+	}
+	want := map[string]int64{thread.URI: 1, pending.URI: 0, "/count-missing": 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Database.CountComment() = %v, want %v", got, want)
+	}
+}
+
+func TestDatabase_CommentLifecycle(t *testing.T) {
+	ctx := context.Background()
+	thread, err := db.NewThread(ctx, "/lifecycle", "lifecycle")
+	if err != nil {
+		t.Fatalf("Database.NewThread() error = %v", err)
+	}
+
+	first, err := db.NewComment(ctx, isso.Comment{Text: "first", Author: "alice", Mode: isso.ModeAccepted},
+		thread.ID, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Database.NewComment() error = %v", err)
+	}
+	reply, err := db.NewComment(ctx, isso.Comment{Text: "reply", Author: "bob", Parent: &first.ID,
+		Mode: isso.ModeAccepted}, thread.ID, "10.0.0.2")
+	if err != nil {
+		t.Fatalf("Database.NewComment() error = %v", err)
+	}
+	nested, err := db.NewComment(ctx, isso.Comment{Text: "nested", Author: "carol", Parent: &reply.ID,
+		Mode: isso.ModeModeration}, thread.ID, "10.0.0.3")
+	if err != nil {
+		t.Fatalf("Database.NewComment() error = %v", err)
+	}
+	if nested.Parent == nil || *nested.Parent != first.ID {
+		t.Errorf("reply of reply should be flattened to %d, got %v", first.ID, nested.Parent)
+	}
+
+	counts, err := db.CountReply(ctx, thread.URI, isso.ModePublic, 0)
+	if err != nil {
+		t.Fatalf("Database.CountReply() error = %v", err)
+	}
+	if counts[0] != 1 || counts[first.ID] != 1 {
+		t.Errorf("Database.CountReply() = %v", counts)
+	}
+	commentsByParent, err := db.FetchCommentsByURI(ctx, thread.URI, first.ID, isso.ModePublic, "id", true)
+	if err != nil {
+		t.Fatalf("Database.FetchCommentsByURI() error = %v", err)
+	}
+	if len(commentsByParent[first.ID]) != 1 || commentsByParent[first.ID][0].ID != reply.ID {
+		t.Errorf("Database.FetchCommentsByURI() = %v", commentsByParent)
+	}
+
+	if likes, _, err := db.VoteComment(ctx, first.ID, "10.0.0.9", true); err != nil || likes != 1 {
+		t.Fatalf("Database.VoteComment() = %d, %v", likes, err)
+	}
+	deleted, err := db.DeleteComment(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("Database.DeleteComment() error = %v", err)
+	}
+	if deleted.Mode != isso.ModeDeleted || deleted.Text != "" || deleted.Likes != 1 {
+		t.Errorf("comment with replies should be soft deleted, got %+v", deleted)
+	}
+
+	restored, err := db.RestoreComment(ctx, isso.Comment{ID: nested.ID + 100, Text: "restored",
+		Author: "dave", Created: 1, Mode: isso.ModeAccepted}, thread.ID)
+	if err != nil {
+		t.Fatalf("Database.RestoreComment() error = %v", err)
+	}
+	if restored.ID != nested.ID+100 || restored.Created != 1 {
+		t.Errorf("Database.RestoreComment() = %+v", restored)
+	}
+	next, err := db.NewComment(ctx, isso.Comment{Text: "next", Author: "erin", Mode: isso.ModeAccepted},
+		thread.ID, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("Database.NewComment() after restore error = %v", err)
+	}
+	if next.ID <= restored.ID {
+		t.Errorf("new id %d should be after restored id %d", next.ID, restored.ID)
+	}
+
+	comments, err := db.ListComments(ctx, thread.ID)
+	if err != nil {
+		t.Fatalf("Database.ListComments() error = %v", err)
+	}
+	if len(comments) != 5 {
+		t.Errorf("Database.ListComments() got %d comments, want 5", len(comments))
+	}
+
+	for _, c := range comments {
+		if _, err := db.DeleteComment(ctx, c.ID); err != nil {
+			t.Fatalf("Database.DeleteComment() error = %v", err)
+		}
+	}
+	if _, err := db.GetThreadByID(ctx, thread.ID); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("thread without comments should be removed, got %v", err)
+	}
+}
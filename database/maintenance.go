@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// Inconsistency is rows of one kind the schema allows but go-isso never leaves behind.
+type Inconsistency struct {
+	Kind        string
+	Description string
+	// Fix tell what Repair does to them.
+	Fix string
+	IDs []int64
+}
+
+// consistencyChecks are run by CheckConsistency in order, a repair may leave rows to the later ones,
+// e.g. a removed comment without thread may be the parent of a reply.
+// find select ids of the inconsistent rows, and fix is executed for every id.
+var consistencyChecks = []struct {
+	kind        string
+	description string
+	fix         string
+	find        string
+	repair      string
+}{
+	{"comment-without-thread", "comments whose thread does not exist", "remove them",
+		"check_comment_without_thread", "comment_delete_hard"},
+	{"reply-without-parent", "replies whose parent does not exist", "make them top comments",
+		"check_reply_without_parent", "comment_unparent"},
+	{"deleted-without-reply", "deleted comments without replies", "remove them",
+		"check_deleted_without_reply", "comment_delete_hard"},
+	{"thread-without-comment", "threads without comments", "remove them",
+		"check_thread_without_comment", "thread_delete"},
+}
+
+// IntegrityCheck return problems found by `PRAGMA integrity_check` of SQLite or `CHECK TABLE` of MySQL,
+// it is empty if none. isso.ErrNotSupported is returned for PostgreSQL.
+func (d *Database) IntegrityCheck(ctx context.Context) ([]string, error) {
+	stmt := d.statement["integrity_check"]
+	if stmt == "" {
+		return nil, wraperror(fmt.Errorf("%w: integrity check of %s", isso.ErrNotSupported, d.driver))
+	}
+	rows, err := d.DB.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		if d.driver == "mysql" {
+			var table, op, msgType, msgText string
+			if err := rows.Scan(&table, &op, &msgType, &msgText); err != nil {
+				return nil, wraperror(err)
+			}
+			if msgType != "status" || msgText != "OK" {
+				problems = append(problems, fmt.Sprintf("%s: %s %s", table, msgType, msgText))
+			}
+			continue
+		}
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, wraperror(err)
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wraperror(err)
+	}
+	return problems, nil
+}
+
+// Vacuum reclaim the space of removed rows and update statistics of the query planner.
+// SQLite needs free space as large as the database to rebuild it.
+func (d *Database) Vacuum(ctx context.Context) error {
+	if _, err := d.DB.ExecContext(ctx, d.statement["vacuum"]); err != nil {
+		return wraperror(err)
+	}
+	return nil
+}
+
+// CheckConsistency return inconsistencies found in the database, it is empty if none.
+func (d *Database) CheckConsistency(ctx context.Context) ([]Inconsistency, error) {
+	var found []Inconsistency
+	for _, check := range consistencyChecks {
+		ids, err := d.findInconsistent(ctx, check.find)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			found = append(found, Inconsistency{Kind: check.kind, Description: check.description,
+				Fix: check.fix, IDs: ids})
+		}
+	}
+	return found, nil
+}
+
+// Repair fix inconsistencies of kind, see Inconsistency.Fix, and return how many rows are fixed.
+// Votes, reactions and rendered html of removed comments are removed too.
+func (d *Database) Repair(ctx context.Context, kind string) (int, error) {
+	for _, check := range consistencyChecks {
+		if check.kind != kind {
+			continue
+		}
+		var n int
+		err := d.withTx(ctx, func(tx *Database) error {
+			ids, err := tx.findInconsistent(ctx, check.find)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if _, err := tx.conn().ExecContext(ctx, tx.statement[check.repair], id); err != nil {
+					return wraperror(err)
+				}
+			}
+			for _, stmt := range []string{"vote_delete_stale", "reaction_delete_stale", "rendered_delete_stale"} {
+				if _, err := tx.conn().ExecContext(ctx, tx.statement[stmt]); err != nil {
+					return wraperror(err)
+				}
+			}
+			n = len(ids)
+			return nil
+		})
+		return n, err
+	}
+	return 0, wraperror(fmt.Errorf("%w: unknown inconsistency %s", isso.ErrInvalidParam, kind))
+}
+
+func (d *Database) findInconsistent(ctx context.Context, find string) ([]int64, error) {
+	rows, err := d.conn().QueryContext(ctx, d.statement[find])
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, wraperror(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wraperror(err)
+	}
+	return ids, nil
+}
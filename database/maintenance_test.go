@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func TestDatabase_CheckConsistency(t *testing.T) {
+	// SQLite only, MySQL refuses comments of missing threads by foreign key
+	d, err := New("", time.Second)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+
+	if problems, err := d.IntegrityCheck(ctx); err != nil || len(problems) != 0 {
+		t.Errorf("Database.IntegrityCheck() = %v, %v", problems, err)
+	}
+	if found, err := d.CheckConsistency(ctx); err != nil || len(found) != 0 {
+		t.Errorf("Database.CheckConsistency() of new database = %+v, %v", found, err)
+	}
+
+	thread, err := d.NewThread(ctx, "/post/", "post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := d.NewThread(ctx, "/empty/", "empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert := `INSERT INTO comments (tid, id, parent, created, mode, remote_addr, text, author, voters)
+		VALUES ($1, $2, $3, 0, $4, '10.0.0.1', '', '', x'00')`
+	for _, c := range []struct {
+		tid    int64
+		id     int64
+		parent interface{}
+		mode   int
+	}{
+		{thread.ID, 1, nil, isso.ModeAccepted},
+		{42, 2, nil, isso.ModeAccepted},        // no thread 42
+		{thread.ID, 3, 100, isso.ModeAccepted}, // no parent 100
+		{thread.ID, 4, nil, isso.ModeDeleted},
+		{thread.ID, 5, nil, isso.ModeDeleted}, // deleted with a deleted reply
+		{thread.ID, 6, 5, isso.ModeDeleted},
+	} {
+		if _, err := d.Exec(insert, c.tid, c.id, c.parent, c.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.React(ctx, 2, "10.0.0.2", "👍"); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := d.CheckConsistency(ctx)
+	if err != nil {
+		t.Fatalf("Database.CheckConsistency() error = %v", err)
+	}
+	want := map[string][]int64{
+		"comment-without-thread": {2},
+		"reply-without-parent":   {3},
+		"deleted-without-reply":  {4, 6},
+		"thread-without-comment": {empty.ID},
+	}
+	got := map[string][]int64{}
+	for _, inconsistency := range found {
+		got[inconsistency.Kind] = inconsistency.IDs
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Database.CheckConsistency() = %v, want %v", got, want)
+	}
+
+	// deleted comment 5 is left without reply after 6 is removed,
+	// and the empty thread is removed by trigger remove_stale_threads on deleting comments
+	for _, repair := range []struct {
+		kind string
+		want int
+	}{
+		{"comment-without-thread", 1},
+		{"reply-without-parent", 1},
+		{"deleted-without-reply", 2},
+		{"deleted-without-reply", 1},
+		{"thread-without-comment", 0},
+	} {
+		if n, err := d.Repair(ctx, repair.kind); err != nil || n != repair.want {
+			t.Errorf("Database.Repair(%s) = %d, %v, want %d", repair.kind, n, err, repair.want)
+		}
+	}
+	if found, err := d.CheckConsistency(ctx); err != nil || len(found) != 0 {
+		t.Errorf("Database.CheckConsistency() after repair = %+v, %v", found, err)
+	}
+	if c, err := d.GetComment(ctx, 3); err != nil || c.Parent != nil {
+		t.Errorf("GetComment() of repaired reply = %+v, %v", c, err)
+	}
+	if reactions, err := d.GetReactions(ctx, 2); err != nil || len(reactions) != 0 {
+		t.Errorf("GetReactions() of removed comment = %v, %v", reactions, err)
+	}
+	if _, err := d.Repair(ctx, "unknown"); !errors.Is(err, isso.ErrInvalidParam) {
+		t.Errorf("Database.Repair(unknown) error = %v, want %v", err, isso.ErrInvalidParam)
+	}
+	if err := d.Vacuum(ctx); err != nil {
+		t.Errorf("Database.Vacuum() error = %v", err)
+	}
+}
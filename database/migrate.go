@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+	"wrong.wang/x/go-isso/logger"
+)
+
+// predictable schema error
+var (
+	// ErrSchemaTooNew is returned when the schema is created by a newer go-isso.
+	ErrSchemaTooNew = errors.New("database: schema is newer than this go-isso supports")
+	// ErrSchemaOutdated is returned when migrations are pending but not allowed to run.
+	ErrSchemaOutdated = errors.New("database: schema is outdated, run `go-isso migrate up` first")
+)
+
+// migration is one step of schema evolution.
+// Its statement and applied are keys of the statement set, so every driver has its own SQL.
+type migration struct {
+	description string
+	statement   string
+	// applied, if not empty, returns a positive count when the step has been done
+	// before schema_version existed, then statement will be skipped.
+	applied string
+}
+
+// migrations are applied in order, version N means the first N migrations are applied.
+// Never change or remove a released migration, append a new one instead.
+var migrations = []migration{
+	{
+		description: "add field notification to comments of old isso database",
+		statement:   "migrate_add_notification",
+		applied:     "migrate_has_notification",
+	},
+	{
+		description: "add table votes for exact voters",
+		statement:   "migrate_add_votes",
+	},
+	{
+		description: "add table reactions",
+		statement:   "migrate_add_reactions",
+	},
+	{
+		description: "add indexes of comments used by fetch and guard queries",
+		statement:   "migrate_add_comment_indexes",
+	},
+	{
+		description: "add table rendered for html of comments",
+		statement:   "migrate_add_rendered",
+	},
+	{
+		description: "add trigger removing threads without comments",
+		statement:   "migrate_add_stale_threads_trigger",
+	},
+}
+
+// LatestSchemaVersion is the schema version this go-isso works with.
+var LatestSchemaVersion = len(migrations)
+
+// SchemaStatus describe the schema version of database.
+type SchemaStatus struct {
+	Current int
+	Latest  int
+	// Pending is the description of migrations not applied yet.
+	Pending []string
+}
+
+// createSchema check the version of an existing database and create tables for a new one.
+// A schema created by a newer go-isso is refused before any other DDL runs on it.
+func (d *Database) createSchema(ctx context.Context) error {
+	// a new database has no threads table
+	var n int64
+	fresh := d.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM threads`).Scan(&n) != nil
+
+	if _, err := d.DB.ExecContext(ctx, d.statement["schema_version_create"]); err != nil {
+		return err
+	}
+	status, err := d.SchemaStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if status.Current > status.Latest {
+		return fmt.Errorf("%w: version %d > %d", ErrSchemaTooNew, status.Current, status.Latest)
+	}
+	if _, err := d.DB.ExecContext(ctx, d.statement["create"]); err != nil {
+		return err
+	}
+	if fresh {
+		if _, err := d.Migrate(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaStatus return the current version and pending migrations
+func (d *Database) SchemaStatus(ctx context.Context) (SchemaStatus, error) {
+	var version null.Int
+	if err := d.DB.QueryRowContext(ctx, d.statement["schema_version_get"]).Scan(&version); err != nil {
+		return SchemaStatus{}, wraperror(err)
+	}
+	status := SchemaStatus{Current: int(version.Int64), Latest: LatestSchemaVersion}
+	for i := status.Current; i < len(migrations); i++ {
+		status.Pending = append(status.Pending, migrations[i].description)
+	}
+	return status, nil
+}
+
+// Migrate apply pending migrations and return how many are applied.
+func (d *Database) Migrate(ctx context.Context) (int, error) {
+	status, err := d.SchemaStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if status.Current > status.Latest {
+		return 0, wraperror(fmt.Errorf("%w: version %d > %d", ErrSchemaTooNew, status.Current, status.Latest))
+	}
+	for version := status.Current + 1; version <= status.Latest; version++ {
+		m := migrations[version-1]
+		logger.Info("migrate %s database to version %d: %s", d.driver, version, m.description)
+		if err := d.migrate(ctx, version, m); err != nil {
+			return version - status.Current - 1, wraperror(fmt.Errorf("migrate to version %d failed: %w", version, err))
+		}
+	}
+	return status.Latest - status.Current, nil
+}
+
+// migrate run one migration and record its version in a transaction.
+// MySQL commits DDL implicitly, so a failed migration may be left half done there.
+func (d *Database) migrate(ctx context.Context, version int, m migration) error {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	done := false
+	if stmt := d.statement[m.applied]; stmt != "" {
+		var n int64
+		if err := tx.QueryRowContext(ctx, stmt).Scan(&n); err != nil {
+			return err
+		}
+		done = n > 0
+	}
+	if stmt := d.statement[m.statement]; stmt != "" && !done {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	now := float64(time.Now().UnixNano()) / float64(1e9)
+	if _, err := tx.ExecContext(ctx, d.statement["schema_version_set"], version, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDatabase_SchemaStatus(t *testing.T) {
+	status, err := db.SchemaStatus(context.Background())
+	if err != nil {
+		t.Fatalf("Database.SchemaStatus() error = %v", err)
+	}
+	if status.Current != LatestSchemaVersion || len(status.Pending) != 0 {
+		t.Errorf("Database.SchemaStatus() = %+v, want version %d", status, LatestSchemaVersion)
+	}
+}
+
+// legacy is the schema of a database created by the python isso before notification.
+const legacy = `
+CREATE TABLE threads (id INTEGER PRIMARY KEY, uri VARCHAR(256) UNIQUE, title VARCHAR(256));
+CREATE TABLE comments (
+	tid REFERENCES threads(id), id INTEGER PRIMARY KEY, parent INTEGER,
+	created FLOAT NOT NULL, modified FLOAT, mode INTEGER, remote_addr VARCHAR,
+	text VARCHAR, author VARCHAR, email VARCHAR, website VARCHAR,
+	likes INTEGER DEFAULT 0, dislikes INTEGER DEFAULT 0, voters BLOB NOT NULL);
+CREATE TABLE preferences (key VARCHAR PRIMARY KEY, value VARCHAR);
+INSERT INTO threads VALUES (1, '/post/', 'post');
+`
+
+func TestDatabase_Migrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-isso-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "comments.db")
+
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Exec(legacy); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	ctx := context.Background()
+	d, err := Open(path, time.Second)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	status, err := d.SchemaStatus(ctx)
+	if err != nil {
+		t.Fatalf("Database.SchemaStatus() error = %v", err)
+	}
+	if status.Current != 0 || len(status.Pending) != LatestSchemaVersion {
+		t.Errorf("Database.SchemaStatus() = %+v, want version 0", status)
+	}
+
+	n, err := d.Migrate(ctx)
+	if err != nil || n != LatestSchemaVersion {
+		t.Fatalf("Database.Migrate() = %d, %v, want %d", n, err, LatestSchemaVersion)
+	}
+	var notification int64
+	if err := d.DB.QueryRow(d.statement["migrate_has_notification"]).Scan(&notification); err != nil || notification != 1 {
+		t.Errorf("notification column = %d, %v, want 1", notification, err)
+	}
+	if n, err := d.Migrate(ctx); err != nil || n != 0 {
+		t.Errorf("Database.Migrate() again = %d, %v, want 0", n, err)
+	}
+
+	// pretend the database is migrated by a newer go-isso, which dropped preferences
+	if _, err := d.DB.Exec(d.statement["schema_version_set"], LatestSchemaVersion+1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.DB.Exec(`DROP TABLE preferences`); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	if _, err := Open(path, time.Second); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Open() error = %v, want %v", err, ErrSchemaTooNew)
+	}
+	// the schema is refused before any table is created
+	conn, err = sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var tables int64
+	err = conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='preferences'`).Scan(&tables)
+	if err != nil || tables != 0 {
+		t.Errorf("preferences tables = %d, %v, want 0", tables, err)
+	}
+}
+
+// TestCreateStatement make sure "create" only creates missing tables, it runs on every start,
+// triggers and functions need privileges a restricted database user may not have.
+func TestCreateStatement(t *testing.T) {
+	for driver, statements := range presetSQL {
+		for _, word := range []string{"TRIGGER", "FUNCTION", "DROP", "ALTER"} {
+			if strings.Contains(strings.ToUpper(statements["create"]), word) {
+				t.Errorf("create statement of %s contains %s", driver, word)
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"context"
+
 	"wrong.wang/x/go-isso/isso"
 	"wrong.wang/x/go-isso/logger"
 )
@@ -9,7 +11,7 @@ import (
 func (d *Database) GetPreference(key string) (string, error) {
 	logger.Debug("key: %s", key)
 	var value string
-	err := d.DB.QueryRow(d.statement["preference_get"], key).Scan(&value)
+	err := d.conn().QueryRowContext(context.Background(), d.statement["preference_get"], key).Scan(&value)
 	if err != nil {
 		return "", wraperror(err)
 	}
@@ -19,7 +21,7 @@ func (d *Database) GetPreference(key string) (string, error) {
 // SetPreference set preference with key value pairs.
 func (d *Database) SetPreference(key string, value string) error {
 	logger.Debug("key: %s, value %s", key, value)
-	result, err := d.DB.Exec(d.statement["preference_set"], key, value)
+	result, err := d.conn().ExecContext(context.Background(), d.statement["preference_set"], key, value)
 	if err != nil {
 		return wraperror(err)
 	}
@@ -32,3 +34,25 @@ func (d *Database) SetPreference(key string, value string) error {
 	}
 	return nil
 }
+
+// ListPreferences return all preferences as key value pairs.
+func (d *Database) ListPreferences() (map[string]string, error) {
+	rows, err := d.conn().QueryContext(context.Background(), d.statement["preference_list"])
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	defer rows.Close()
+
+	preferences := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, wraperror(err)
+		}
+		preferences[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wraperror(err)
+	}
+	return preferences, nil
+}
@@ -1,9 +1,11 @@
 package database
 
 import (
+	"encoding/hex"
 	"errors"
 	"testing"
 
+	"wrong.wang/x/go-isso/config"
 	"wrong.wang/x/go-isso/isso"
 )
 
@@ -51,3 +53,28 @@ func TestDatabase_SetPreference(t *testing.T) {
 		}
 	})
 }
+
+// TestNew_keys start isso with the database of GO_ISSO_TEST_DSN, the keys it creates
+// must be saved by every backend and reused by the next start.
+func TestNew_keys(t *testing.T) {
+	d := newTestStorage(false)(t).(*Database)
+	isso.New(config.Config{}, d)
+	keys := map[string]string{}
+	for name, size := range map[string]int{isso.HashKeyPreference: 64, isso.BlockKeyPreference: 32} {
+		value, err := d.GetPreference(name)
+		if err != nil {
+			t.Fatalf("GetPreference(%s) error = %v", name, err)
+		}
+		if key, err := hex.DecodeString(value); err != nil || len(key) != size {
+			t.Errorf("GetPreference(%s) = %q, want %d hex encoded bytes", name, value, size)
+		}
+		keys[name] = value
+	}
+
+	isso.New(config.Config{}, d)
+	for name, want := range keys {
+		if got, err := d.GetPreference(name); err != nil || got != want {
+			t.Errorf("GetPreference(%s) after restart = %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
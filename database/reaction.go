@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/logger"
+)
+
+// React add reaction of voter to comment id and return reaction counts of the comment.
+func (d *Database) React(ctx context.Context, id int64, voter string, reaction string) (map[string]int, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("react %s to comment %d", reaction, id)
+
+	hashed, err := d.hashVoter(voter)
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	var counts map[string]int
+	err = d.withTx(ctx, func(tx *Database) error {
+		nc, err := tx.getComment(ctx, id)
+		if err != nil {
+			return err
+		}
+		// the author can not react to its own comment, as it can not vote it
+		if voter == nc.RemoteAddr {
+			return isso.ErrAlreadyVoted
+		}
+		var n int64
+		if err := tx.conn().QueryRowContext(ctx, tx.statement["reaction_exist"], id, hashed, reaction).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			return isso.ErrAlreadyVoted
+		}
+		err = tx.execstmt(ctx, nil, nil, tx.statement["reaction_new"], id, hashed, reaction,
+			float64(time.Now().UnixNano())/float64(1e9))
+		if isUniqueViolation(err) {
+			// the same reaction is added since it was checked
+			return isso.ErrAlreadyVoted
+		}
+		if err != nil {
+			return err
+		}
+		counts, err = tx.countReactions(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	return counts, nil
+}
+
+// Unreact retract reaction of voter from comment id and return reaction counts of the comment.
+func (d *Database) Unreact(ctx context.Context, id int64, voter string, reaction string) (map[string]int, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("unreact %s from comment %d", reaction, id)
+
+	hashed, err := d.hashVoter(voter)
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	var counts map[string]int
+	err = d.withTx(ctx, func(tx *Database) error {
+		if _, err := tx.getComment(ctx, id); err != nil {
+			return err
+		}
+		var rowsaffected int64
+		err := tx.execstmt(ctx, &rowsaffected, nil, tx.statement["reaction_delete"], id, hashed, reaction)
+		if err != nil {
+			return err
+		}
+		if rowsaffected != 1 {
+			return isso.ErrNotVoted
+		}
+		counts, err = tx.countReactions(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	return counts, nil
+}
+
+// GetReactions return reaction counts of comment id.
+func (d *Database) GetReactions(ctx context.Context, id int64) (map[string]int, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("get reactions of comment %d", id)
+
+	counts, err := d.countReactions(ctx, id)
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	return counts, nil
+}
+
+func (d *Database) countReactions(ctx context.Context, id int64) (map[string]int, error) {
+	rows, err := d.conn().QueryContext(ctx, d.statement["reaction_count"], id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var reaction string
+		var n int
+		if err := rows.Scan(&reaction, &n); err != nil {
+			return nil, err
+		}
+		counts[reaction] = n
+	}
+	return counts, rows.Err()
+}
+
+// CountReactions return reaction counts of comments of uri by comment id.
+func (d *Database) CountReactions(ctx context.Context, uri string) (map[int64]map[string]int, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("uri: %s", uri)
+
+	rows, err := d.conn().QueryContext(ctx, d.statement["reaction_count_by_uri"], uri)
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	defer rows.Close()
+
+	counts := map[int64]map[string]int{}
+	for rows.Next() {
+		var id int64
+		var reaction string
+		var n int
+		if err := rows.Scan(&id, &reaction, &n); err != nil {
+			return nil, wraperror(err)
+		}
+		if counts[id] == nil {
+			counts[id] = map[string]int{}
+		}
+		counts[id][reaction] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wraperror(err)
+	}
+	return counts, nil
+}
+
+// ListReactions return reactions of comments of thread, ordered by comment id, voter and reaction.
+func (d *Database) ListReactions(ctx context.Context, threadID int64) ([]isso.Reaction, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("list reactions of %d", threadID)
+
+	rows, err := d.conn().QueryContext(ctx, d.statement["reaction_list"], threadID)
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	defer rows.Close()
+
+	var reactions []isso.Reaction
+	for rows.Next() {
+		var r isso.Reaction
+		if err := rows.Scan(&r.CommentID, &r.Voter, &r.Reaction, &r.Created); err != nil {
+			return nil, wraperror(err)
+		}
+		reactions = append(reactions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wraperror(err)
+	}
+	return reactions, nil
+}
+
+// RestoreReaction save r as it is.
+func (d *Database) RestoreReaction(ctx context.Context, r isso.Reaction) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("restore reaction %s of comment %d", r.Reaction, r.CommentID)
+
+	err := d.execstmt(ctx, nil, nil, d.statement["reaction_new"], r.CommentID, r.Voter, r.Reaction, r.Created)
+	if err != nil {
+		return wraperror(err)
+	}
+	return nil
+}
+
+// ReactionGuard limit reactions of voter in 60s to ratelimit
+func (d *Database) ReactionGuard(ctx context.Context, voter string, ratelimit int) (bool, string) {
+	hashed, err := d.hashVoter(voter)
+	if err != nil {
+		return false, fmt.Sprintf("hash voter failed: %v", err)
+	}
+	var n int
+	d.conn().QueryRowContext(ctx, d.statement["reaction_guard_ratelimit"],
+		hashed, float64(time.Now().UnixNano())/float64(1e9)).Scan(&n)
+	if n > ratelimit {
+		return false, fmt.Sprintf("%s ratelimit exceeded: %d reactions in 60s", voter, n)
+	}
+	return true, ""
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func TestDatabase_React(t *testing.T) {
+	ctx := context.Background()
+	thread, err := db.NewThread(ctx, "/reactions", "reactions")
+	if err != nil {
+		t.Fatalf("Database.NewThread() error = %v", err)
+	}
+	c, err := db.NewComment(ctx, isso.Comment{Text: "react me", Mode: isso.ModeAccepted}, thread.ID, "10.7.0.1")
+	if err != nil {
+		t.Fatalf("Database.NewComment() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		unreact  bool
+		voter    string
+		reaction string
+		want     map[string]int
+		wantErr  error
+	}{
+		{"thumbs up", false, "10.7.0.2", "👍", map[string]int{"👍": 1}, nil},
+		{"thumbs up again", false, "10.7.0.2", "👍", nil, isso.ErrAlreadyVoted},
+		{"author", false, "10.7.0.1", "👍", nil, isso.ErrAlreadyVoted},
+		{"another type", false, "10.7.0.2", "🎉", map[string]int{"👍": 1, "🎉": 1}, nil},
+		{"another voter", false, "10.7.0.3", "👍", map[string]int{"👍": 2, "🎉": 1}, nil},
+		{"unreact", true, "10.7.0.2", "🎉", map[string]int{"👍": 2}, nil},
+		{"unreact again", true, "10.7.0.2", "🎉", nil, isso.ErrNotVoted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			react := db.React
+			if tt.unreact {
+				react = db.Unreact
+			}
+			got, err := react(ctx, c.ID, tt.voter, tt.reaction)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Database.React() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Database.React() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := db.React(ctx, c.ID+1000, "10.7.0.2", "👍"); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("Database.React() to missing comment error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+	byURI, err := db.CountReactions(ctx, thread.URI)
+	if err != nil {
+		t.Fatalf("Database.CountReactions() error = %v", err)
+	}
+	if want := map[int64]map[string]int{c.ID: {"👍": 2}}; !reflect.DeepEqual(byURI, want) {
+		t.Errorf("Database.CountReactions() = %v, want %v", byURI, want)
+	}
+	if ok, _ := db.ReactionGuard(ctx, "10.7.0.2", 0); ok {
+		t.Errorf("Database.ReactionGuard() should deny a reaction in 60s with ratelimit 0")
+	}
+	if ok, reason := db.ReactionGuard(ctx, "10.7.0.9", 0); !ok {
+		t.Errorf("Database.ReactionGuard() = %s", reason)
+	}
+
+	// React map it to isso.ErrAlreadyVoted, the reaction is added since it was checked
+	hashed, err := db.hashVoter("10.7.0.3")
+	if err != nil {
+		t.Fatalf("Database.hashVoter() error = %v", err)
+	}
+	err = db.execstmt(ctx, nil, nil, db.statement["reaction_new"], c.ID, hashed, "👍", 0.0)
+	if !isUniqueViolation(err) {
+		t.Errorf("isUniqueViolation(%v) = false, want true for a reaction given twice", err)
+	}
+
+	if _, err := db.DeleteComment(ctx, c.ID); err != nil {
+		t.Fatalf("Database.DeleteComment() error = %v", err)
+	}
+	if got, err := db.GetReactions(ctx, c.ID); err != nil || len(got) != 0 {
+		t.Errorf("reactions of deleted comment = %v, %v", got, err)
+	}
+}
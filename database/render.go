@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/logger"
+)
+
+// SetRendered save r as the html of comment id, replacing the saved one.
+func (d *Database) SetRendered(ctx context.Context, id int64, r isso.Rendered) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("set rendered html of comment %d by %s", id, r.Renderer)
+
+	err := d.withTx(ctx, func(tx *Database) error {
+		if _, err := tx.getComment(ctx, id); err != nil {
+			return err
+		}
+		return tx.execstmt(ctx, nil, nil, tx.statement["rendered_set"], id, r.Renderer, r.Checksum, r.HTML)
+	})
+	if err != nil {
+		return wraperror(err)
+	}
+	return nil
+}
+
+// GetRendered return the saved html of comments of uri by comment id.
+func (d *Database) GetRendered(ctx context.Context, uri string) (map[int64]isso.Rendered, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("uri: %s", uri)
+
+	rows, err := d.conn().QueryContext(ctx, d.statement["rendered_get_by_uri"], uri)
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	defer rows.Close()
+
+	rendered := map[int64]isso.Rendered{}
+	for rows.Next() {
+		var id int64
+		var r isso.Rendered
+		if err := rows.Scan(&id, &r.Renderer, &r.Checksum, &r.HTML); err != nil {
+			return nil, wraperror(err)
+		}
+		rendered[id] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wraperror(err)
+	}
+	return rendered, nil
+}
@@ -1,6 +1,9 @@
 package database
 
 var (
+	// presetSQLITE3 is the statement set for SQLite, its schema is the one of the python isso.
+	// comments.tid is declared without a type there, so it is compared with +threads.id,
+	// or the INTEGER affinity of threads.id keeps the comments_tid index from being used.
 	presetSQLITE3 map[string]string = map[string]string{
 		"create": `
 		CREATE TABLE IF NOT EXISTS comments (
@@ -29,36 +32,96 @@ var (
 			uri VARCHAR(256) UNIQUE,
 			title VARCHAR(256)
 		);
-		CREATE TRIGGER IF NOT EXISTS remove_stale_threads
-    	AFTER DELETE ON comments
-    	BEGIN
-    		DELETE FROM threads WHERE id NOT IN (SELECT tid FROM comments);
-    	END;
 		`,
 		"migrate_add_notification": `ALTER TABLE comments ADD COLUMN notification INTEGER DEFAULT 0;`,
+		"migrate_has_notification": `SELECT COUNT(*) FROM pragma_table_info('comments') WHERE name='notification';`,
+		"migrate_add_votes": `CREATE TABLE IF NOT EXISTS votes (
+			comment_id BIGINT NOT NULL,
+			voter CHAR(64) NOT NULL,
+			value INTEGER NOT NULL,
+			created FLOAT NOT NULL,
+			PRIMARY KEY (comment_id, voter)
+		);`,
+		"migrate_add_reactions": `CREATE TABLE IF NOT EXISTS reactions (
+			comment_id BIGINT NOT NULL,
+			voter CHAR(64) NOT NULL,
+			reaction VARCHAR(32) NOT NULL,
+			created FLOAT NOT NULL,
+			PRIMARY KEY (comment_id, voter, reaction)
+		);`,
+		"migrate_add_comment_indexes": `CREATE INDEX IF NOT EXISTS comments_tid ON comments (tid);
+			CREATE INDEX IF NOT EXISTS comments_parent ON comments (parent);
+			CREATE INDEX IF NOT EXISTS comments_remote_addr ON comments (remote_addr);
+			CREATE INDEX IF NOT EXISTS comments_created ON comments (created);`,
+		"migrate_add_rendered": `CREATE TABLE IF NOT EXISTS rendered (
+			comment_id INTEGER PRIMARY KEY,
+			renderer VARCHAR NOT NULL,
+			checksum VARCHAR NOT NULL,
+			html TEXT NOT NULL
+		);`,
+		// databases of the python isso have the trigger already
+		"migrate_add_stale_threads_trigger": `CREATE TRIGGER IF NOT EXISTS remove_stale_threads
+			AFTER DELETE ON comments
+			BEGIN
+				DELETE FROM threads WHERE id NOT IN (SELECT tid FROM comments);
+			END;`,
+
+		"schema_version_create": `CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			applied FLOAT NOT NULL
+		);`,
+		"schema_version_get": `SELECT MAX(version) FROM schema_version;`,
+		"schema_version_set": `INSERT INTO schema_version (version, applied) VALUES ($1, $2);`,
+
+		"integrity_check": `PRAGMA integrity_check;`,
+		"vacuum":          `VACUUM;`,
+		"check_comment_without_thread": `SELECT id FROM comments
+			WHERE tid IS NULL OR tid NOT IN (SELECT id FROM threads) ORDER BY id;`,
+		"check_reply_without_parent": `SELECT id FROM comments
+			WHERE parent IS NOT NULL AND parent NOT IN (SELECT id FROM comments) ORDER BY id;`,
+		"check_deleted_without_reply": `SELECT id FROM comments
+			WHERE mode=4 AND id NOT IN (SELECT parent FROM comments WHERE parent IS NOT NULL) ORDER BY id;`,
+		"check_thread_without_comment": `SELECT id FROM threads
+			WHERE id NOT IN (SELECT tid FROM comments WHERE tid IS NOT NULL) ORDER BY id;`,
+		"comment_unparent": `UPDATE comments SET parent=NULL WHERE id=$1;`,
+		"thread_delete":    `DELETE FROM threads WHERE id=$1;`,
 
-		"preference_get": `SELECT value FROM preferences WHERE key=$1;`,
-		"preference_set": `INSERT INTO preferences (key, value) VALUES ($1, $2);`,
+		"preference_get":  `SELECT value FROM preferences WHERE key=$1;`,
+		"preference_set":  `INSERT INTO preferences (key, value) VALUES ($1, $2);`,
+		"preference_list": `SELECT key, value FROM preferences;`,
 
 		"thread_get_by_uri": `SELECT * FROM threads WHERE uri=$1;`,
 		"thread_get_by_id":  `SELECT * FROM threads WHERE id=$1;`,
 		"thread_new":        `INSERT INTO threads (uri, title) VALUES ($1, $2);`,
+		"thread_restore":    `INSERT INTO threads (id, uri, title) VALUES ($1, $2, $3);`,
+		"thread_list":       `SELECT * FROM threads ORDER BY id;`,
+		"thread_get_by_comment_id": `SELECT threads.id, threads.uri, threads.title
+			FROM threads INNER JOIN comments ON comments.tid=threads.id WHERE comments.id=$1;`,
 
 		"comment_new": `INSERT INTO comments (
         	tid, parent, created, modified, mode, remote_addr,
 			text, author, email, website, voters, notification
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);`,
+		"comment_restore": `INSERT INTO comments (
+			tid, id, parent, created, modified, mode, remote_addr, text, author,
+			email, website, likes, dislikes, voters, notification
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15);`,
 		"comment_get_by_id": `SELECT * FROM comments WHERE id=$1`,
+		// SQLite locks the whole database on write, there is no row lock
+		"comment_get_by_id_for_update": `SELECT * FROM comments WHERE id=$1`,
+		"comment_list":                 `SELECT * FROM comments WHERE tid=$1 ORDER BY id`,
 		"comment_is_previously_approved_author": `SELECT CASE WHEN EXISTS(
 			SELECT * FROM comments WHERE email=$1 AND mode=1 AND created > strftime("%s", DATETIME("now", "-6 month"))
 		) THEN 1 ELSE 0 END;`,
 		"comment_count_reply": `SELECT comments.parent,count(*)
-			FROM comments INNER JOIN threads ON threads.uri=$1 AND comments.tid=threads.id AND
+			FROM comments INNER JOIN threads ON threads.uri=$1 AND comments.tid=+threads.id AND
 			   ($2 | comments.mode = $3) AND comments.created > $4 GROUP BY comments.parent`,
 		"comment_fetch_by_uri": `SELECT comments.* FROM comments INNER JOIN threads ON
-			threads.uri=? AND comments.tid=threads.id AND (? | comments.mode) = ?`,
-		"comment_count": `SELECT threads.uri, COUNT(comments.id) FROM comments LEFT OUTER JOIN 
-		threads ON threads.id = tid AND comments.mode = 1 GROUP BY threads.uri`,
+			threads.uri=? AND comments.tid=+threads.id AND (? | comments.mode) = ?`,
+		"comment_fetch_by_uri_top":    ` AND comments.parent IS NULL `,
+		"comment_fetch_by_uri_parent": ` AND comments.parent=? `,
+		"comment_count": `SELECT threads.uri, COUNT(comments.id) FROM comments INNER JOIN
+		threads ON threads.id = comments.tid WHERE comments.mode = 1 GROUP BY threads.uri`,
 		"comment_activate":     `UPDATE comments SET mode=1 WHERE id=$1 AND mode=2;`,
 		"comment_unsubscribe":  `UPDATE comments SET notification=0 WHERE email=$1 AND (id=$2 OR parent=$2);`,
 		"comment_edit":         `UPDATE comments SET text=$1,author=$2,website=$3,modified=$4,email=$5 WHERE id=$6`,
@@ -67,16 +130,53 @@ var (
 		"comment_delete_soft":  `UPDATE comments SET mode=4, text='', author='', website=NULL WHERE id=?`,
 		"comment_delete_stale": `DELETE FROM comments 
 		WHERE mode=4 AND id NOT IN (SELECT parent FROM comments WHERE parent IS NOT NULL)`,
-		"comment_vote_set": `UPDATE comments SET likes=?, dislikes=?, voters=? WHERE id=?`,
+		"comment_vote": `UPDATE comments SET likes=likes+?, dislikes=dislikes+?, voters=?
+			WHERE id=? AND likes=? AND dislikes=?`,
+		"comment_vote_add":   `UPDATE comments SET likes=likes+$1, dislikes=dislikes+$2 WHERE id=$3`,
+		"comment_voters_set": `UPDATE comments SET voters=$1 WHERE id=$2`,
+
+		"vote_get":     `SELECT value FROM votes WHERE comment_id=$1 AND voter=$2`,
+		"vote_new":     `INSERT INTO votes (comment_id, voter, value, created) VALUES ($1, $2, $3, $4)`,
+		"vote_set":     `UPDATE votes SET value=$1 WHERE comment_id=$2 AND voter=$3`,
+		"vote_retract": `UPDATE votes SET value=0 WHERE comment_id=$1 AND voter=$2 AND value=$3`,
+		"vote_count": `SELECT COALESCE(SUM(CASE WHEN value=1 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN value=-1 THEN 1 ELSE 0 END), 0) FROM votes WHERE comment_id=$1`,
+		"vote_delete_stale": `DELETE FROM votes WHERE comment_id NOT IN (SELECT id FROM comments)`,
+		"vote_list": `SELECT votes.comment_id, votes.voter, votes.value, votes.created FROM votes
+			INNER JOIN comments ON comments.id=votes.comment_id WHERE comments.tid=$1
+			ORDER BY votes.comment_id, votes.voter`,
+
+		"reaction_new":          `INSERT INTO reactions (comment_id, voter, reaction, created) VALUES ($1, $2, $3, $4)`,
+		"reaction_exist":        `SELECT COUNT(*) FROM reactions WHERE comment_id=$1 AND voter=$2 AND reaction=$3`,
+		"reaction_delete":       `DELETE FROM reactions WHERE comment_id=$1 AND voter=$2 AND reaction=$3`,
+		"reaction_delete_stale": `DELETE FROM reactions WHERE comment_id NOT IN (SELECT id FROM comments)`,
+		"reaction_count":        `SELECT reaction, COUNT(*) FROM reactions WHERE comment_id=$1 GROUP BY reaction`,
+		"reaction_count_by_uri": `SELECT reactions.comment_id, reactions.reaction, COUNT(*) FROM reactions
+			INNER JOIN comments ON comments.id=reactions.comment_id
+			INNER JOIN threads ON comments.tid=+threads.id AND threads.uri=$1
+			GROUP BY reactions.comment_id, reactions.reaction`,
+		"reaction_list": `SELECT reactions.comment_id, reactions.voter, reactions.reaction, reactions.created
+			FROM reactions INNER JOIN comments ON comments.id=reactions.comment_id WHERE comments.tid=$1
+			ORDER BY reactions.comment_id, reactions.voter, reactions.reaction`,
+		"reaction_guard_ratelimit": `SELECT COUNT(*) FROM reactions WHERE voter=$1 AND $2 - created < 60`,
+
+		"rendered_set": `INSERT OR REPLACE INTO rendered (comment_id, renderer, checksum, html) VALUES ($1, $2, $3, $4)`,
+		"rendered_get_by_uri": `SELECT rendered.comment_id, rendered.renderer, rendered.checksum, rendered.html FROM rendered
+			INNER JOIN comments ON comments.id=rendered.comment_id
+			INNER JOIN threads ON comments.tid=+threads.id AND threads.uri=$1`,
+		"rendered_delete_stale": `DELETE FROM rendered WHERE comment_id NOT IN (SELECT id FROM comments)`,
+		"rendered_delete":       `DELETE FROM rendered WHERE comment_id=$1`,
 
 		"comment_guard_ratelimit": `SELECT COUNT(id) FROM comments WHERE remote_addr = ? AND ? - created < 60;`,
 		"comment_guard_3_direct_comment": `SELECT COUNT(id) FROM comments
-			WHERE tid = (SELECT id FROM threads WHERE uri = ?) AND remote_addr = ? AND parent IS NULL;`,
+			WHERE tid = +(SELECT id FROM threads WHERE uri = ?) AND remote_addr = ? AND parent IS NULL;`,
 		"comment_guard_reply_to_self": `SELECT COUNT(id) FROM comments
 			WHERE remote_addr = ? AND id = ? AND ? - created < ?;`,
 	}
 )
 
 var presetSQL map[string]map[string]string = map[string]map[string]string{
-	"sqlite3": presetSQLITE3,
+	"sqlite3":  presetSQLITE3,
+	"postgres": presetPOSTGRES,
+	"mysql":    presetMYSQL,
 }
@@ -0,0 +1,182 @@
+package database
+
+var (
+	// presetMYSQL is the statement set for MySQL and MariaDB.
+	// MySQL can not select from the table being deleted, so the subquery of
+	// comment_delete_stale is materialized with DISTINCT first.
+	presetMYSQL map[string]string = map[string]string{
+		"create": `
+		CREATE TABLE IF NOT EXISTS threads (
+			id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+			uri VARCHAR(255) COLLATE utf8mb4_bin UNIQUE,
+			title VARCHAR(255)
+		) DEFAULT CHARSET=utf8mb4;
+		CREATE TABLE IF NOT EXISTS comments (
+			tid BIGINT,
+			id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+			parent BIGINT,
+			created DOUBLE NOT NULL,
+			modified DOUBLE,
+			mode INTEGER,
+			remote_addr VARCHAR(64),
+			text MEDIUMTEXT,
+			author VARCHAR(255),
+			email VARCHAR(255),
+			website VARCHAR(255),
+			likes INTEGER DEFAULT 0,
+			dislikes INTEGER DEFAULT 0,
+			voters BLOB NOT NULL,
+			notification INTEGER DEFAULT 0,
+			FOREIGN KEY (tid) REFERENCES threads(id)
+		) DEFAULT CHARSET=utf8mb4;
+		CREATE TABLE IF NOT EXISTS preferences (
+			name VARCHAR(255) COLLATE utf8mb4_bin PRIMARY KEY,
+			value TEXT
+		) DEFAULT CHARSET=utf8mb4;
+		`,
+		"migrate_add_notification": `ALTER TABLE comments ADD COLUMN notification INTEGER DEFAULT 0;`,
+		"migrate_has_notification": `SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_schema=DATABASE() AND table_name='comments' AND column_name='notification';`,
+		"migrate_add_votes": `CREATE TABLE IF NOT EXISTS votes (
+			comment_id BIGINT NOT NULL,
+			voter CHAR(64) NOT NULL,
+			value INTEGER NOT NULL,
+			created DOUBLE NOT NULL,
+			PRIMARY KEY (comment_id, voter)
+		);`,
+		"migrate_add_reactions": `CREATE TABLE IF NOT EXISTS reactions (
+			comment_id BIGINT NOT NULL,
+			voter CHAR(64) NOT NULL,
+			reaction VARCHAR(32) COLLATE utf8mb4_bin NOT NULL,
+			created DOUBLE NOT NULL,
+			PRIMARY KEY (comment_id, voter, reaction)
+		) DEFAULT CHARSET=utf8mb4;`,
+		// the foreign key of tid has an index already, MySQL has no CREATE INDEX IF NOT EXISTS.
+		"migrate_add_comment_indexes": `CREATE INDEX comments_parent ON comments (parent);
+			CREATE INDEX comments_remote_addr ON comments (remote_addr);
+			CREATE INDEX comments_created ON comments (created);`,
+		"migrate_add_rendered": `CREATE TABLE IF NOT EXISTS rendered (
+			comment_id BIGINT PRIMARY KEY,
+			renderer VARCHAR(64) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			html MEDIUMTEXT NOT NULL
+		) DEFAULT CHARSET=utf8mb4;`,
+		// the TRIGGER privilege, and SUPER with binary logging unless log_bin_trust_function_creators
+		// is set, is needed once for this migration only. Databases created by older go-isso
+		// have the trigger already, it is replaced.
+		"migrate_add_stale_threads_trigger": `DROP TRIGGER IF EXISTS remove_stale_threads;
+			CREATE TRIGGER remove_stale_threads
+			AFTER DELETE ON comments
+			FOR EACH ROW DELETE FROM threads WHERE id NOT IN (SELECT tid FROM comments);`,
+
+		"schema_version_create": `CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			applied DOUBLE NOT NULL
+		);`,
+		"schema_version_get": `SELECT MAX(version) FROM schema_version;`,
+		"schema_version_set": `INSERT INTO schema_version (version, applied) VALUES (?, ?);`,
+
+		"integrity_check": `CHECK TABLE threads, comments, preferences, votes, reactions, rendered, schema_version;`,
+		"vacuum":          `OPTIMIZE TABLE threads, comments, preferences, votes, reactions, rendered;`,
+		"check_comment_without_thread": `SELECT id FROM comments
+			WHERE tid IS NULL OR tid NOT IN (SELECT id FROM threads) ORDER BY id;`,
+		"check_reply_without_parent": `SELECT id FROM comments
+			WHERE parent IS NOT NULL AND parent NOT IN (SELECT id FROM comments) ORDER BY id;`,
+		"check_deleted_without_reply": `SELECT id FROM comments
+			WHERE mode=4 AND id NOT IN (SELECT parent FROM comments WHERE parent IS NOT NULL) ORDER BY id;`,
+		"check_thread_without_comment": `SELECT id FROM threads
+			WHERE id NOT IN (SELECT tid FROM comments WHERE tid IS NOT NULL) ORDER BY id;`,
+		"comment_unparent": `UPDATE comments SET parent=NULL WHERE id=?;`,
+		"thread_delete":    `DELETE FROM threads WHERE id=?;`,
+
+		// `key` is reserved by MySQL, so preferences use `name` as key.
+		"preference_get":  `SELECT value FROM preferences WHERE name=?;`,
+		"preference_set":  `INSERT INTO preferences (name, value) VALUES (?, ?);`,
+		"preference_list": `SELECT name, value FROM preferences;`,
+
+		"thread_get_by_uri": `SELECT id, uri, title FROM threads WHERE uri=?;`,
+		"thread_get_by_id":  `SELECT id, uri, title FROM threads WHERE id=?;`,
+		"thread_new":        `INSERT INTO threads (uri, title) VALUES (?, ?);`,
+		"thread_restore":    `INSERT INTO threads (id, uri, title) VALUES (?, ?, ?);`,
+		"thread_list":       `SELECT id, uri, title FROM threads ORDER BY id;`,
+		"thread_get_by_comment_id": `SELECT threads.id, threads.uri, threads.title
+			FROM threads INNER JOIN comments ON comments.tid=threads.id WHERE comments.id=?;`,
+
+		"comment_new": `INSERT INTO comments (
+			tid, parent, created, modified, mode, remote_addr,
+			text, author, email, website, voters, notification
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		"comment_restore": `INSERT INTO comments (
+			tid, id, parent, created, modified, mode, remote_addr, text, author,
+			email, website, likes, dislikes, voters, notification
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+		"comment_get_by_id":            `SELECT * FROM comments WHERE id=?`,
+		"comment_get_by_id_for_update": `SELECT * FROM comments WHERE id=? FOR UPDATE`,
+		"comment_list":                 `SELECT * FROM comments WHERE tid=? ORDER BY id`,
+		"comment_is_previously_approved_author": `SELECT CASE WHEN EXISTS(
+			SELECT * FROM comments WHERE email=? AND mode=1
+			AND created > UNIX_TIMESTAMP(DATE_SUB(NOW(), INTERVAL 6 MONTH))
+		) THEN 1 ELSE 0 END;`,
+		"comment_count_reply": `SELECT comments.parent, COUNT(*)
+			FROM comments INNER JOIN threads ON threads.uri=? AND comments.tid=threads.id AND
+			(? | comments.mode) = ? AND comments.created > ? GROUP BY comments.parent`,
+		"comment_fetch_by_uri": `SELECT comments.* FROM comments INNER JOIN threads ON
+			threads.uri=? AND comments.tid=threads.id AND (? | comments.mode) = ?`,
+		"comment_fetch_by_uri_top":    ` AND comments.parent IS NULL `,
+		"comment_fetch_by_uri_parent": ` AND comments.parent=? `,
+		"comment_count": `SELECT threads.uri, COUNT(comments.id) FROM comments INNER JOIN
+		threads ON threads.id = comments.tid WHERE comments.mode = 1 GROUP BY threads.uri`,
+		"comment_activate": `UPDATE comments SET mode=1 WHERE id=? AND mode=2;`,
+		// args: email, id, id
+		"comment_unsubscribe":  `UPDATE comments SET notification=0 WHERE email=? AND (id=? OR parent=?);`,
+		"comment_edit":         `UPDATE comments SET text=?,author=?,website=?,modified=?,email=? WHERE id=?`,
+		"comment_delete_check": `SELECT COUNT(*) FROM comments WHERE parent=?`,
+		"comment_delete_hard":  `DELETE FROM comments WHERE id=?`,
+		"comment_delete_soft":  `UPDATE comments SET mode=4, text='', author='', website=NULL WHERE id=?`,
+		"comment_delete_stale": `DELETE FROM comments WHERE mode=4 AND id NOT IN (
+			SELECT parent FROM (SELECT DISTINCT parent FROM comments WHERE parent IS NOT NULL) AS referenced)`,
+		"comment_vote": `UPDATE comments SET likes=likes+?, dislikes=dislikes+?, voters=?
+			WHERE id=? AND likes=? AND dislikes=?`,
+		"comment_vote_add":   `UPDATE comments SET likes=likes+?, dislikes=dislikes+? WHERE id=?`,
+		"comment_voters_set": `UPDATE comments SET voters=? WHERE id=?`,
+
+		"vote_get":     `SELECT value FROM votes WHERE comment_id=? AND voter=?`,
+		"vote_new":     `INSERT INTO votes (comment_id, voter, value, created) VALUES (?, ?, ?, ?)`,
+		"vote_set":     `UPDATE votes SET value=? WHERE comment_id=? AND voter=?`,
+		"vote_retract": `UPDATE votes SET value=0 WHERE comment_id=? AND voter=? AND value=?`,
+		"vote_count": `SELECT COALESCE(SUM(CASE WHEN value=1 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN value=-1 THEN 1 ELSE 0 END), 0) FROM votes WHERE comment_id=?`,
+		"vote_delete_stale": `DELETE FROM votes WHERE comment_id NOT IN (SELECT id FROM comments)`,
+		"vote_list": `SELECT votes.comment_id, votes.voter, votes.value, votes.created FROM votes
+			INNER JOIN comments ON comments.id=votes.comment_id WHERE comments.tid=?
+			ORDER BY votes.comment_id, votes.voter`,
+
+		"reaction_new":          `INSERT INTO reactions (comment_id, voter, reaction, created) VALUES (?, ?, ?, ?)`,
+		"reaction_exist":        `SELECT COUNT(*) FROM reactions WHERE comment_id=? AND voter=? AND reaction=?`,
+		"reaction_delete":       `DELETE FROM reactions WHERE comment_id=? AND voter=? AND reaction=?`,
+		"reaction_delete_stale": `DELETE FROM reactions WHERE comment_id NOT IN (SELECT id FROM comments)`,
+		"reaction_count":        `SELECT reaction, COUNT(*) FROM reactions WHERE comment_id=? GROUP BY reaction`,
+		"reaction_count_by_uri": `SELECT reactions.comment_id, reactions.reaction, COUNT(*) FROM reactions
+			INNER JOIN comments ON comments.id=reactions.comment_id
+			INNER JOIN threads ON threads.id=comments.tid AND threads.uri=?
+			GROUP BY reactions.comment_id, reactions.reaction`,
+		"reaction_list": `SELECT reactions.comment_id, reactions.voter, reactions.reaction, reactions.created
+			FROM reactions INNER JOIN comments ON comments.id=reactions.comment_id WHERE comments.tid=?
+			ORDER BY reactions.comment_id, reactions.voter, reactions.reaction`,
+		"reaction_guard_ratelimit": `SELECT COUNT(*) FROM reactions WHERE voter=? AND ? - created < 60`,
+
+		"rendered_set": `INSERT INTO rendered (comment_id, renderer, checksum, html) VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE renderer=VALUES(renderer), checksum=VALUES(checksum), html=VALUES(html)`,
+		"rendered_get_by_uri": `SELECT rendered.comment_id, rendered.renderer, rendered.checksum, rendered.html FROM rendered
+			INNER JOIN comments ON comments.id=rendered.comment_id
+			INNER JOIN threads ON comments.tid=threads.id AND threads.uri=?`,
+		"rendered_delete_stale": `DELETE FROM rendered WHERE comment_id NOT IN (SELECT id FROM comments)`,
+		"rendered_delete":       `DELETE FROM rendered WHERE comment_id=?`,
+
+		"comment_guard_ratelimit": `SELECT COUNT(id) FROM comments WHERE remote_addr = ? AND ? - created < 60;`,
+		"comment_guard_3_direct_comment": `SELECT COUNT(id) FROM comments
+			WHERE tid = (SELECT id FROM threads WHERE uri = ?) AND remote_addr = ? AND parent IS NULL;`,
+		"comment_guard_reply_to_self": `SELECT COUNT(id) FROM comments
+			WHERE remote_addr = ? AND id = ? AND ? - created < ?;`,
+	}
+)
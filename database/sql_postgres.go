@@ -0,0 +1,185 @@
+package database
+
+var (
+	// presetPOSTGRES is the statement set for PostgreSQL.
+	// postgres can not return LastInsertId, so every INSERT ends with `RETURNING id`,
+	// and the sequence must be moved after a row is restored with its own id.
+	presetPOSTGRES map[string]string = map[string]string{
+		"create": `
+		CREATE TABLE IF NOT EXISTS threads (
+			id BIGSERIAL PRIMARY KEY,
+			uri VARCHAR(256) UNIQUE,
+			title VARCHAR(256)
+		);
+		CREATE TABLE IF NOT EXISTS comments (
+			tid BIGINT REFERENCES threads(id),
+			id BIGSERIAL PRIMARY KEY,
+			parent BIGINT,
+			created DOUBLE PRECISION NOT NULL,
+			modified DOUBLE PRECISION,
+			mode INTEGER,
+			remote_addr VARCHAR,
+			text VARCHAR,
+			author VARCHAR,
+			email VARCHAR,
+			website VARCHAR,
+			likes INTEGER DEFAULT 0,
+			dislikes INTEGER DEFAULT 0,
+			voters BYTEA NOT NULL,
+			notification INTEGER DEFAULT 0
+		);
+		CREATE TABLE IF NOT EXISTS preferences (
+			key VARCHAR PRIMARY KEY,
+			value VARCHAR
+		);
+		`,
+		"migrate_add_notification": `ALTER TABLE comments ADD COLUMN IF NOT EXISTS notification INTEGER DEFAULT 0;`,
+		"migrate_add_votes": `CREATE TABLE IF NOT EXISTS votes (
+			comment_id BIGINT NOT NULL,
+			voter CHAR(64) NOT NULL,
+			value INTEGER NOT NULL,
+			created DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (comment_id, voter)
+		);`,
+		"migrate_add_reactions": `CREATE TABLE IF NOT EXISTS reactions (
+			comment_id BIGINT NOT NULL,
+			voter CHAR(64) NOT NULL,
+			reaction VARCHAR(32) NOT NULL,
+			created DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (comment_id, voter, reaction)
+		);`,
+		"migrate_add_comment_indexes": `CREATE INDEX IF NOT EXISTS comments_tid ON comments (tid);
+			CREATE INDEX IF NOT EXISTS comments_parent ON comments (parent);
+			CREATE INDEX IF NOT EXISTS comments_remote_addr ON comments (remote_addr);
+			CREATE INDEX IF NOT EXISTS comments_created ON comments (created);`,
+		"migrate_add_rendered": `CREATE TABLE IF NOT EXISTS rendered (
+			comment_id BIGINT PRIMARY KEY,
+			renderer VARCHAR(64) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			html TEXT NOT NULL
+		);`,
+		// databases created by older go-isso have the trigger already, it is replaced once
+		"migrate_add_stale_threads_trigger": `CREATE OR REPLACE FUNCTION remove_stale_threads() RETURNS TRIGGER AS $$
+		BEGIN
+			DELETE FROM threads WHERE id NOT IN (SELECT tid FROM comments);
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+		DROP TRIGGER IF EXISTS remove_stale_threads ON comments;
+		CREATE TRIGGER remove_stale_threads
+		AFTER DELETE ON comments
+		FOR EACH STATEMENT EXECUTE PROCEDURE remove_stale_threads();`,
+
+		"schema_version_create": `CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			applied DOUBLE PRECISION NOT NULL
+		);`,
+		"schema_version_get": `SELECT MAX(version) FROM schema_version;`,
+		"schema_version_set": `INSERT INTO schema_version (version, applied) VALUES ($1, $2);`,
+
+		// PostgreSQL has no integrity check statement, its pages are checked by amcheck.
+		"vacuum": `VACUUM ANALYZE;`,
+		"check_comment_without_thread": `SELECT id FROM comments
+			WHERE tid IS NULL OR tid NOT IN (SELECT id FROM threads) ORDER BY id;`,
+		"check_reply_without_parent": `SELECT id FROM comments
+			WHERE parent IS NOT NULL AND parent NOT IN (SELECT id FROM comments) ORDER BY id;`,
+		"check_deleted_without_reply": `SELECT id FROM comments
+			WHERE mode=4 AND id NOT IN (SELECT parent FROM comments WHERE parent IS NOT NULL) ORDER BY id;`,
+		"check_thread_without_comment": `SELECT id FROM threads
+			WHERE id NOT IN (SELECT tid FROM comments WHERE tid IS NOT NULL) ORDER BY id;`,
+		"comment_unparent": `UPDATE comments SET parent=NULL WHERE id=$1;`,
+		"thread_delete":    `DELETE FROM threads WHERE id=$1;`,
+
+		"preference_get":  `SELECT value FROM preferences WHERE key=$1;`,
+		"preference_set":  `INSERT INTO preferences (key, value) VALUES ($1, $2);`,
+		"preference_list": `SELECT key, value FROM preferences;`,
+
+		"thread_get_by_uri": `SELECT id, uri, title FROM threads WHERE uri=$1;`,
+		"thread_get_by_id":  `SELECT id, uri, title FROM threads WHERE id=$1;`,
+		"thread_new":        `INSERT INTO threads (uri, title) VALUES ($1, $2) RETURNING id;`,
+		"thread_restore": `INSERT INTO threads (id, uri, title)
+			VALUES (COALESCE($1, nextval(pg_get_serial_sequence('threads', 'id'))), $2, $3) RETURNING id;`,
+		"thread_restore_sequence": `SELECT setval(pg_get_serial_sequence('threads', 'id'), MAX(id)) FROM threads;`,
+		"thread_list":             `SELECT id, uri, title FROM threads ORDER BY id;`,
+		"thread_get_by_comment_id": `SELECT threads.id, threads.uri, threads.title
+			FROM threads INNER JOIN comments ON comments.tid=threads.id WHERE comments.id=$1;`,
+
+		"comment_new": `INSERT INTO comments (
+			tid, parent, created, modified, mode, remote_addr,
+			text, author, email, website, voters, notification
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id;`,
+		"comment_restore": `INSERT INTO comments (
+			tid, id, parent, created, modified, mode, remote_addr, text, author,
+			email, website, likes, dislikes, voters, notification
+		) VALUES ($1, COALESCE($2, nextval(pg_get_serial_sequence('comments', 'id'))),
+			$3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id;`,
+		"comment_restore_sequence":     `SELECT setval(pg_get_serial_sequence('comments', 'id'), MAX(id)) FROM comments;`,
+		"comment_get_by_id":            `SELECT * FROM comments WHERE id=$1`,
+		"comment_get_by_id_for_update": `SELECT * FROM comments WHERE id=$1 FOR UPDATE`,
+		"comment_list":                 `SELECT * FROM comments WHERE tid=$1 ORDER BY id`,
+		"comment_is_previously_approved_author": `SELECT CASE WHEN EXISTS(
+			SELECT * FROM comments WHERE email=$1 AND mode=1
+			AND created > EXTRACT(EPOCH FROM NOW() - INTERVAL '6 months')
+		) THEN 1 ELSE 0 END;`,
+		"comment_count_reply": `SELECT comments.parent, COUNT(*)
+			FROM comments INNER JOIN threads ON threads.uri=$1 AND comments.tid=threads.id AND
+			($2 | comments.mode) = $3 AND comments.created > $4 GROUP BY comments.parent`,
+		"comment_fetch_by_uri": `SELECT comments.* FROM comments INNER JOIN threads ON
+			threads.uri=$1 AND comments.tid=threads.id AND ($2 | comments.mode) = $3`,
+		"comment_fetch_by_uri_top":    ` AND comments.parent IS NULL `,
+		"comment_fetch_by_uri_parent": ` AND comments.parent=$4 `,
+		"comment_count": `SELECT threads.uri, COUNT(comments.id) FROM comments INNER JOIN
+		threads ON threads.id = comments.tid WHERE comments.mode = 1 GROUP BY threads.uri`,
+		"comment_activate":     `UPDATE comments SET mode=1 WHERE id=$1 AND mode=2;`,
+		"comment_unsubscribe":  `UPDATE comments SET notification=0 WHERE email=$1 AND (id=$2 OR parent=$2);`,
+		"comment_edit":         `UPDATE comments SET text=$1,author=$2,website=$3,modified=$4,email=$5 WHERE id=$6`,
+		"comment_delete_check": `SELECT COUNT(*) FROM comments WHERE parent=$1`,
+		"comment_delete_hard":  `DELETE FROM comments WHERE id=$1`,
+		"comment_delete_soft":  `UPDATE comments SET mode=4, text='', author='', website=NULL WHERE id=$1`,
+		"comment_delete_stale": `DELETE FROM comments
+		WHERE mode=4 AND id NOT IN (SELECT parent FROM comments WHERE parent IS NOT NULL)`,
+		"comment_vote": `UPDATE comments SET likes=likes+$1, dislikes=dislikes+$2, voters=$3
+			WHERE id=$4 AND likes=$5 AND dislikes=$6`,
+		"comment_vote_add":   `UPDATE comments SET likes=likes+$1, dislikes=dislikes+$2 WHERE id=$3`,
+		"comment_voters_set": `UPDATE comments SET voters=$1 WHERE id=$2`,
+
+		"vote_get":     `SELECT value FROM votes WHERE comment_id=$1 AND voter=$2`,
+		"vote_new":     `INSERT INTO votes (comment_id, voter, value, created) VALUES ($1, $2, $3, $4)`,
+		"vote_set":     `UPDATE votes SET value=$1 WHERE comment_id=$2 AND voter=$3`,
+		"vote_retract": `UPDATE votes SET value=0 WHERE comment_id=$1 AND voter=$2 AND value=$3`,
+		"vote_count": `SELECT COALESCE(SUM(CASE WHEN value=1 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN value=-1 THEN 1 ELSE 0 END), 0) FROM votes WHERE comment_id=$1`,
+		"vote_delete_stale": `DELETE FROM votes WHERE comment_id NOT IN (SELECT id FROM comments)`,
+		"vote_list": `SELECT votes.comment_id, votes.voter, votes.value, votes.created FROM votes
+			INNER JOIN comments ON comments.id=votes.comment_id WHERE comments.tid=$1
+			ORDER BY votes.comment_id, votes.voter`,
+
+		"reaction_new":          `INSERT INTO reactions (comment_id, voter, reaction, created) VALUES ($1, $2, $3, $4)`,
+		"reaction_exist":        `SELECT COUNT(*) FROM reactions WHERE comment_id=$1 AND voter=$2 AND reaction=$3`,
+		"reaction_delete":       `DELETE FROM reactions WHERE comment_id=$1 AND voter=$2 AND reaction=$3`,
+		"reaction_delete_stale": `DELETE FROM reactions WHERE comment_id NOT IN (SELECT id FROM comments)`,
+		"reaction_count":        `SELECT reaction, COUNT(*) FROM reactions WHERE comment_id=$1 GROUP BY reaction`,
+		"reaction_count_by_uri": `SELECT reactions.comment_id, reactions.reaction, COUNT(*) FROM reactions
+			INNER JOIN comments ON comments.id=reactions.comment_id
+			INNER JOIN threads ON threads.id=comments.tid AND threads.uri=$1
+			GROUP BY reactions.comment_id, reactions.reaction`,
+		"reaction_list": `SELECT reactions.comment_id, reactions.voter, reactions.reaction, reactions.created
+			FROM reactions INNER JOIN comments ON comments.id=reactions.comment_id WHERE comments.tid=$1
+			ORDER BY reactions.comment_id, reactions.voter, reactions.reaction`,
+		"reaction_guard_ratelimit": `SELECT COUNT(*) FROM reactions WHERE voter=$1 AND $2 - created < 60`,
+
+		"rendered_set": `INSERT INTO rendered (comment_id, renderer, checksum, html) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (comment_id) DO UPDATE SET renderer=EXCLUDED.renderer, checksum=EXCLUDED.checksum, html=EXCLUDED.html`,
+		"rendered_get_by_uri": `SELECT rendered.comment_id, rendered.renderer, rendered.checksum, rendered.html FROM rendered
+			INNER JOIN comments ON comments.id=rendered.comment_id
+			INNER JOIN threads ON comments.tid=threads.id AND threads.uri=$1`,
+		"rendered_delete_stale": `DELETE FROM rendered WHERE comment_id NOT IN (SELECT id FROM comments)`,
+		"rendered_delete":       `DELETE FROM rendered WHERE comment_id=$1`,
+
+		"comment_guard_ratelimit": `SELECT COUNT(id) FROM comments WHERE remote_addr = $1 AND $2 - created < 60;`,
+		"comment_guard_3_direct_comment": `SELECT COUNT(id) FROM comments
+			WHERE tid = (SELECT id FROM threads WHERE uri = $1) AND remote_addr = $2 AND parent IS NULL;`,
+		"comment_guard_reply_to_self": `SELECT COUNT(id) FROM comments
+			WHERE remote_addr = $1 AND id = $2 AND $3 - created < $4;`,
+	}
+)
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/isso/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, newTestStorage(false))
+}
+
+func TestStorageExactVotes(t *testing.T) {
+	storagetest.Run(t, newTestStorage(true))
+}
+
+func newTestStorage(exactVotes bool) storagetest.NewStorage {
+	dsn := os.Getenv("GO_ISSO_TEST_DSN")
+	return func(t *testing.T) isso.Storage {
+		// every test needs an empty database, the tables are dropped and created again
+		if dsn != "" {
+			if err := dropTables(dsn); err != nil {
+				t.Fatalf("drop tables of %s failed: %v", dsn, err)
+			}
+		}
+		d, err := New(dsn, time.Second)
+		if err != nil {
+			t.Fatalf("init database failed: %v", err)
+		}
+		d.ExactVotes = exactVotes
+		t.Cleanup(func() { d.Close() })
+		return d
+	}
+}
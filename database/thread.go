@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 
+	"gopkg.in/guregu/null.v4"
 	"wrong.wang/x/go-isso/isso"
 	"wrong.wang/x/go-isso/logger"
 )
@@ -13,7 +14,7 @@ func (d *Database) GetThreadByURI(ctx context.Context, uri string) (isso.Thread,
 	var thread isso.Thread
 	ctx, cancel := d.withTimeout(ctx)
 	defer cancel()
-	err := d.DB.QueryRowContext(ctx, d.statement["thread_get_by_uri"], uri).Scan(&thread.ID, &thread.URI, &thread.Title)
+	err := d.conn().QueryRowContext(ctx, d.statement["thread_get_by_uri"], uri).Scan(&thread.ID, &thread.URI, &thread.Title)
 	if err != nil {
 		return thread, wraperror(err)
 	}
@@ -26,7 +27,20 @@ func (d *Database) GetThreadByID(ctx context.Context, id int64) (isso.Thread, er
 	var thread isso.Thread
 	ctx, cancel := d.withTimeout(ctx)
 	defer cancel()
-	err := d.DB.QueryRowContext(ctx, d.statement["thread_get_by_id"], id).Scan(&thread.ID, &thread.URI, &thread.Title)
+	err := d.conn().QueryRowContext(ctx, d.statement["thread_get_by_id"], id).Scan(&thread.ID, &thread.URI, &thread.Title)
+	if err != nil {
+		return thread, wraperror(err)
+	}
+	return thread, nil
+}
+
+// GetThreadByCommentID get the thread of comment cid
+func (d *Database) GetThreadByCommentID(ctx context.Context, cid int64) (isso.Thread, error) {
+	logger.Debug("comment id %d", cid)
+	var thread isso.Thread
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	err := d.conn().QueryRowContext(ctx, d.statement["thread_get_by_comment_id"], cid).Scan(&thread.ID, &thread.URI, &thread.Title)
 	if err != nil {
 		return thread, wraperror(err)
 	}
@@ -43,13 +57,59 @@ func (d *Database) NewThread(ctx context.Context, uri string, title string) (iss
 		return isso.Thread{}, wraperror(isso.ErrInvalidParam)
 	}
 
-	var rowsaffected, lastinsertid int64
-	err := d.execstmt(ctx, &rowsaffected, &lastinsertid, d.statement["thread_new"], uri, title)
+	id, err := d.insert(ctx, d.statement["thread_new"], uri, title)
+	if err != nil {
+		return isso.Thread{}, wraperror(err)
+	}
+	return isso.Thread{ID: id, URI: uri, Title: title}, nil
+}
+
+// RestoreThread save thread with its id, used by importers
+func (d *Database) RestoreThread(ctx context.Context, t isso.Thread) (isso.Thread, error) {
+	logger.Debug("restore thread %d %s", t.ID, t.URI)
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	if t.URI == "" {
+		return isso.Thread{}, wraperror(isso.ErrInvalidParam)
+	}
+
+	id, err := d.insert(ctx, d.statement["thread_restore"], null.NewInt(t.ID, t.ID != 0), t.URI, t.Title)
 	if err != nil {
 		return isso.Thread{}, wraperror(err)
 	}
-	if rowsaffected != 1 {
-		return isso.Thread{}, wraperror(isso.ErrNotExpectAmount)
+	if stmt, ok := d.statement["thread_restore_sequence"]; ok && t.ID != 0 {
+		if err := d.execstmt(ctx, nil, nil, stmt); err != nil {
+			return isso.Thread{}, wraperror(err)
+		}
+	}
+	t.ID = id
+	return t, nil
+}
+
+// ListThreads return all threads
+func (d *Database) ListThreads(ctx context.Context) ([]isso.Thread, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := d.conn().QueryContext(ctx, d.statement["thread_list"])
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	defer rows.Close()
+
+	var threads []isso.Thread
+	for rows.Next() {
+		var thread isso.Thread
+		var title null.String
+		if err := rows.Scan(&thread.ID, &thread.URI, &title); err != nil {
+			return nil, wraperror(err)
+		}
+		thread.Title = title.String
+		threads = append(threads, thread)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wraperror(err)
 	}
-	return isso.Thread{ID: lastinsertid, URI: uri, Title: title}, nil
+	return threads, nil
 }
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn return the transaction d runs in, or the connection pool.
+func (d *Database) conn() querier {
+	if d.tx != nil {
+		return d.tx
+	}
+	return d.DB
+}
+
+// WithTx run fn in a transaction, commit if fn return nil, rollback otherwise.
+// fn must only use the storage it is given, the outer one may wait for the connection fn holds.
+// Nested calls join the outer transaction.
+func (d *Database) WithTx(ctx context.Context, fn func(isso.Storage) error) error {
+	return d.withTx(ctx, func(tx *Database) error { return fn(tx) })
+}
+
+func (d *Database) withTx(ctx context.Context, fn func(*Database) error) error {
+	if d.tx != nil {
+		return fn(d)
+	}
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return wraperror(err)
+	}
+	txd := *d
+	txd.tx = tx
+	if err := fn(&txd); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return wraperror(err)
+	}
+	return nil
+}
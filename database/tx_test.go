@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func TestDatabase_WithTx(t *testing.T) {
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+	err := db.WithTx(ctx, func(s isso.Storage) error {
+		thread, err := s.NewThread(ctx, "/tx-rollback", "rollback")
+		if err != nil {
+			return err
+		}
+		// nested call joins the outer transaction
+		return s.WithTx(ctx, func(s isso.Storage) error {
+			if _, err := s.NewComment(ctx, isso.Comment{Text: "gone", Mode: isso.ModeAccepted}, thread.ID, "10.0.1.1"); err != nil {
+				return err
+			}
+			return rollback
+		})
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Database.WithTx() error = %v, want %v", err, rollback)
+	}
+	if _, err := db.GetThreadByURI(ctx, "/tx-rollback"); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("thread of rolled back transaction should not exist, got %v", err)
+	}
+
+	err = db.WithTx(ctx, func(s isso.Storage) error {
+		_, err := s.NewThread(ctx, "/tx-commit", "commit")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Database.WithTx() error = %v", err)
+	}
+	if _, err := db.GetThreadByURI(ctx, "/tx-commit"); err != nil {
+		t.Errorf("thread of committed transaction should exist, got %v", err)
+	}
+}
+
+func TestDatabase_Concurrent(t *testing.T) {
+	const n = 50
+	ctx := context.Background()
+	thread, err := db.NewThread(ctx, "/concurrent", "concurrent")
+	if err != nil {
+		t.Fatalf("Database.NewThread() error = %v", err)
+	}
+	root, err := db.NewComment(ctx, isso.Comment{Text: "root", Mode: isso.ModeAccepted}, thread.ID, "10.0.2.1")
+	if err != nil {
+		t.Fatalf("Database.NewComment() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*n)
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			c := isso.Comment{Text: fmt.Sprintf("reply %d", i), Parent: &root.ID, Mode: isso.ModeAccepted}
+			if _, err := db.NewComment(ctx, c, thread.ID, fmt.Sprintf("10.0.3.%d", i)); err != nil {
+				errs <- err
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := db.VoteComment(ctx, root.ID, fmt.Sprintf("10.0.4.%d", i), true)
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+
+	root, err = db.GetComment(ctx, root.ID)
+	if err != nil {
+		t.Fatalf("Database.GetComment() error = %v", err)
+	}
+	if root.Likes != n {
+		t.Errorf("%d concurrent votes got %d likes", n, root.Likes)
+	}
+	comments, err := db.ListComments(ctx, thread.ID)
+	if err != nil {
+		t.Fatalf("Database.ListComments() error = %v", err)
+	}
+	if len(comments) != n+1 {
+		t.Errorf("%d concurrent comments got %d", n+1, len(comments))
+	}
+
+	// deleting the root and its replies in any order removes all of them
+	for _, c := range comments {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			if _, err := db.DeleteComment(ctx, id); err != nil && !errors.Is(err, isso.ErrStorageNotFound) {
+				errs <- err
+			}
+		}(c.ID)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent operation error = %v", err)
+		}
+	}
+	if _, err := db.GetThreadByURI(ctx, "/concurrent"); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("thread without comments should be removed, got %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/logger"
+	"wrong.wang/x/go-isso/tool/bloomfilter"
+)
+
+// voteRetries is how many times VoteComment retry when the comment is voted at the same time.
+const voteRetries = 5
+
+// vote values in votes table
+const (
+	voteLike    = 1
+	voteDislike = -1
+)
+
+// VoteComment vote comment id, the voter is checked and recorded in the same transaction.
+// Voters are remembered in the bloom filter of comment, with ExactVotes they are in votes
+// table too and can change the vote, otherwise a comment accepts at most isso.MaxVotes votes.
+func (d *Database) VoteComment(ctx context.Context, id int64, voter string, up bool) (int, int, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("vote comment %d", id)
+
+	var hashed string
+	if d.ExactVotes {
+		var err error
+		if hashed, err = d.hashVoter(voter); err != nil {
+			return 0, 0, wraperror(err)
+		}
+	}
+	var likes, dislikes int
+	vote := func(tx *Database) error {
+		nc, err := tx.getComment(ctx, id)
+		if err != nil {
+			return err
+		}
+		likes, dislikes = nc.Likes, nc.Dislikes
+		if tx.ExactVotes {
+			return tx.voteExact(ctx, nc, voter, hashed, up, &likes, &dislikes)
+		}
+		if likes+dislikes > isso.MaxVotes {
+			return isso.ErrTooManyVotes
+		}
+		bf := recoverVoters(nc)
+		if bf.Contains([]byte(voter)) {
+			return isso.ErrAlreadyVoted
+		}
+		bf.Add([]byte(voter))
+		buf := bf.Buffer()
+
+		dlikes, ddislikes := 0, 1
+		if up {
+			dlikes, ddislikes = 1, 0
+		}
+		// the update is conditional, in case the comment is voted since it was read
+		var rowsaffected int64
+		err = tx.execstmt(ctx, &rowsaffected, nil, tx.statement["comment_vote"],
+			dlikes, ddislikes, buf[:], id, likes, dislikes)
+		if err != nil {
+			return err
+		}
+		if rowsaffected != 1 {
+			return isso.ErrNotExpectAmount
+		}
+		likes, dislikes = likes+dlikes, dislikes+ddislikes
+		return nil
+	}
+
+	var err error
+	for i := 0; i < voteRetries; i++ {
+		if err = d.withTx(ctx, vote); !errors.Is(err, isso.ErrNotExpectAmount) {
+			break
+		}
+	}
+	if err != nil {
+		return likes, dislikes, wraperror(err)
+	}
+	return likes, dislikes, nil
+}
+
+// voteExact record the vote in votes table, a like replace a dislike and vice versa.
+func (d *Database) voteExact(ctx context.Context, nc nullComment, voter, hashed string, up bool, likes, dislikes *int) error {
+	value := voteDislike
+	if up {
+		value = voteLike
+	}
+
+	var old int
+	err := d.conn().QueryRowContext(ctx, d.statement["vote_get"], nc.ID, hashed).Scan(&old)
+	if err == sql.ErrNoRows {
+		old, err = d.newVoter(ctx, nc, voter, hashed)
+	}
+	if err != nil {
+		return err
+	}
+	if old == value {
+		return isso.ErrAlreadyVoted
+	}
+	if err := d.execstmt(ctx, nil, nil, d.statement["vote_set"], value, nc.ID, hashed); err != nil {
+		return err
+	}
+
+	dlikes, ddislikes := countVote(value)
+	if old != 0 {
+		l, dl := countVote(old)
+		dlikes, ddislikes = dlikes-l, ddislikes-dl
+	}
+	if err := d.execstmt(ctx, nil, nil, d.statement["comment_vote_add"], dlikes, ddislikes, nc.ID); err != nil {
+		return err
+	}
+	*likes, *dislikes = *likes+dlikes, *dislikes+ddislikes
+	return nil
+}
+
+// newVoter add voter, who is not in votes table yet, to votes table and return the vote it had.
+// A new voter has no vote yet, it is added to the bloom filter of nc as well while the filter
+// is reliable, so the vote is still known if ExactVotes is disabled again.
+// A voter already in the bloom filter voted before votes table was used, see bloomVote.
+func (d *Database) newVoter(ctx context.Context, nc nullComment, voter, hashed string) (int, error) {
+	var value int
+	bf := recoverVoters(nc)
+	switch {
+	case bf.Contains([]byte(voter)):
+		// the author is in the bloom filter without a vote
+		if voter == nc.RemoteAddr {
+			return 0, isso.ErrAlreadyVoted
+		}
+		var exactLikes, exactDislikes int
+		err := d.conn().QueryRowContext(ctx, d.statement["vote_count"], nc.ID).Scan(&exactLikes, &exactDislikes)
+		if err != nil {
+			return 0, err
+		}
+		var ok bool
+		if value, ok = bloomVote(nc.Likes-exactLikes, nc.Dislikes-exactDislikes); !ok {
+			return 0, isso.ErrAlreadyVoted
+		}
+	case nc.Likes+nc.Dislikes < isso.MaxVotes:
+		bf.Add([]byte(voter))
+		buf := bf.Buffer()
+		if err := d.execstmt(ctx, nil, nil, d.statement["comment_voters_set"], buf[:], nc.ID); err != nil {
+			return 0, err
+		}
+	}
+	err := d.execstmt(ctx, nil, nil, d.statement["vote_new"], nc.ID, hashed, value,
+		float64(time.Now().UnixNano())/float64(1e9))
+	return value, err
+}
+
+// bloomVote return the vote of a voter found in the bloom filter of a comment, given the likes
+// and dislikes of the comment not in votes table. The vote is migrated if they are all likes
+// or all dislikes, ok is false if they are mixed and the vote can not be told.
+// Without them the voter is a false positive of the bloom filter and has no vote.
+func bloomVote(likes, dislikes int) (value int, ok bool) {
+	switch {
+	case likes <= 0 && dislikes <= 0:
+		return 0, true
+	case dislikes <= 0:
+		return voteLike, true
+	case likes <= 0:
+		return voteDislike, true
+	}
+	return 0, false
+}
+
+// UnvoteComment retract the like or dislike of voter from comment id.
+// It needs ExactVotes, isso.ErrNotVoted is returned if voter has no such vote.
+// The voter is kept in votes table without a vote, it is in the bloom filter of the comment.
+func (d *Database) UnvoteComment(ctx context.Context, id int64, voter string, up bool) (int, int, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("unvote comment %d", id)
+
+	if !d.ExactVotes {
+		return 0, 0, wraperror(isso.ErrNotSupported)
+	}
+	value := voteDislike
+	if up {
+		value = voteLike
+	}
+	hashed, err := d.hashVoter(voter)
+	if err != nil {
+		return 0, 0, wraperror(err)
+	}
+
+	var likes, dislikes int
+	err = d.withTx(ctx, func(tx *Database) error {
+		nc, err := tx.getComment(ctx, id)
+		if err != nil {
+			return err
+		}
+		likes, dislikes = nc.Likes, nc.Dislikes
+
+		var old int
+		err = tx.conn().QueryRowContext(ctx, tx.statement["vote_get"], id, hashed).Scan(&old)
+		if err == sql.ErrNoRows {
+			if !recoverVoters(nc).Contains([]byte(voter)) {
+				return isso.ErrNotVoted
+			}
+			// a vote in the bloom filter only is migrated before it is retracted
+			if old, err = tx.newVoter(ctx, nc, voter, hashed); errors.Is(err, isso.ErrAlreadyVoted) {
+				return isso.ErrNotVoted
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if old != value {
+			return isso.ErrNotVoted
+		}
+		var rowsaffected int64
+		err = tx.execstmt(ctx, &rowsaffected, nil, tx.statement["vote_retract"], id, hashed, value)
+		if err != nil {
+			return err
+		}
+		if rowsaffected != 1 {
+			return isso.ErrNotVoted
+		}
+		dlikes, ddislikes := countVote(value)
+		if err := tx.execstmt(ctx, nil, nil, tx.statement["comment_vote_add"], -dlikes, -ddislikes, id); err != nil {
+			return err
+		}
+		likes, dislikes = likes-dlikes, dislikes-ddislikes
+		return nil
+	})
+	if err != nil {
+		return likes, dislikes, wraperror(err)
+	}
+	return likes, dislikes, nil
+}
+
+// ListVotes return exact votes of comments of thread, ordered by comment id and voter.
+func (d *Database) ListVotes(ctx context.Context, threadID int64) ([]isso.Vote, error) {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("list votes of %d", threadID)
+
+	rows, err := d.conn().QueryContext(ctx, d.statement["vote_list"], threadID)
+	if err != nil {
+		return nil, wraperror(err)
+	}
+	defer rows.Close()
+
+	var votes []isso.Vote
+	for rows.Next() {
+		var v isso.Vote
+		if err := rows.Scan(&v.CommentID, &v.Voter, &v.Value, &v.Created); err != nil {
+			return nil, wraperror(err)
+		}
+		votes = append(votes, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wraperror(err)
+	}
+	return votes, nil
+}
+
+// RestoreVote save v as it is, the likes and dislikes of its comment are not changed.
+func (d *Database) RestoreVote(ctx context.Context, v isso.Vote) error {
+	ctx, cancel := d.withTimeout(ctx)
+	defer cancel()
+	logger.Debug("restore vote of comment %d", v.CommentID)
+
+	err := d.execstmt(ctx, nil, nil, d.statement["vote_new"], v.CommentID, v.Voter, v.Value, v.Created)
+	if err != nil {
+		return wraperror(err)
+	}
+	return nil
+}
+
+func countVote(value int) (likes int, dislikes int) {
+	if value == voteLike {
+		return 1, 0
+	}
+	return 0, 1
+}
+
+func recoverVoters(nc nullComment) *bloomfilter.Bloomfilter {
+	var buf [256]byte
+	copy(buf[:], nc.Voters)
+	return bloomfilter.RecoverFrom(buf, nc.Likes+nc.Dislikes)
+}
+
+// hashVoter keep voter identity, e.g. the IP address, out of votes and reactions tables.
+// It must not run in a transaction of d, the key may be created on the first call.
+func (d *Database) hashVoter(voter string) (string, error) {
+	return d.voters.Hash(d, voter)
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func TestDatabase_VoteCommentConcurrent(t *testing.T) {
+	const likes, dislikes = 100, 40
+	ctx := context.Background()
+	thread, err := db.NewThread(ctx, "/votes", "votes")
+	if err != nil {
+		t.Fatalf("Database.NewThread() error = %v", err)
+	}
+	c, err := db.NewComment(ctx, isso.Comment{Text: "vote me", Mode: isso.ModeAccepted}, thread.ID, "10.1.0.1")
+	if err != nil {
+		t.Fatalf("Database.NewComment() error = %v", err)
+	}
+
+	// every voter votes twice at the same time, the second vote must be denied
+	var wg sync.WaitGroup
+	var denied int64
+	for i := 0; i < likes+dislikes; i++ {
+		for j := 0; j < 2; j++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, _, err := db.VoteComment(ctx, c.ID, fmt.Sprintf("10.2.%d.%d", i/256, i%256), i < likes)
+				switch {
+				case errors.Is(err, isso.ErrAlreadyVoted):
+					atomic.AddInt64(&denied, 1)
+				case err != nil:
+					t.Errorf("Database.VoteComment() error = %v", err)
+				}
+			}(i)
+		}
+	}
+	wg.Wait()
+
+	c, err = db.GetComment(ctx, c.ID)
+	if err != nil {
+		t.Fatalf("Database.GetComment() error = %v", err)
+	}
+	if c.Likes != likes || c.Dislikes != dislikes || denied != likes+dislikes {
+		t.Errorf("got %d likes, %d dislikes, %d denied, want %d, %d, %d",
+			c.Likes, c.Dislikes, denied, likes, dislikes, likes+dislikes)
+	}
+
+	if _, _, err := db.VoteComment(ctx, c.ID, "10.1.0.1", true); !errors.Is(err, isso.ErrAlreadyVoted) {
+		t.Errorf("author vote own comment error = %v, want %v", err, isso.ErrAlreadyVoted)
+	}
+	for i := 0; i < isso.MaxVotes; i++ {
+		db.VoteComment(ctx, c.ID, fmt.Sprintf("10.3.%d.%d", i/256, i%256), true)
+	}
+	if _, _, err := db.VoteComment(ctx, c.ID, "10.4.0.1", true); !errors.Is(err, isso.ErrTooManyVotes) {
+		t.Errorf("Database.VoteComment() error = %v, want %v", err, isso.ErrTooManyVotes)
+	}
+}
+
+func TestDatabase_ExactVotes(t *testing.T) {
+	ctx := context.Background()
+	exact := *db
+	exact.ExactVotes = true
+
+	thread, err := exact.NewThread(ctx, "/exact-votes", "exact votes")
+	if err != nil {
+		t.Fatalf("Database.NewThread() error = %v", err)
+	}
+	c, err := exact.NewComment(ctx, isso.Comment{Text: "vote me", Mode: isso.ModeAccepted}, thread.ID, "10.5.0.1")
+	if err != nil {
+		t.Fatalf("Database.NewComment() error = %v", err)
+	}
+	// votes recorded in the bloom filter before are migrated, see storagetest
+	if _, _, err := db.VoteComment(ctx, c.ID, "10.5.0.2", true); err != nil {
+		t.Fatalf("Database.VoteComment() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		unvote       bool
+		voter        string
+		up           bool
+		wantLikes    int
+		wantDislikes int
+		wantErr      error
+	}{
+		{"author", false, "10.5.0.1", true, 1, 0, isso.ErrAlreadyVoted},
+		{"bloom filter voter", false, "10.5.0.2", true, 1, 0, isso.ErrAlreadyVoted},
+		{"bloom filter voter switch", false, "10.5.0.2", false, 0, 1, nil},
+		{"bloom filter voter unvote", true, "10.5.0.2", false, 0, 0, nil},
+		{"like", false, "10.5.0.3", true, 1, 0, nil},
+		{"like again", false, "10.5.0.3", true, 1, 0, isso.ErrAlreadyVoted},
+		{"switch to dislike", false, "10.5.0.3", false, 0, 1, nil},
+		{"unvote like", true, "10.5.0.3", true, 0, 1, isso.ErrNotVoted},
+		{"unvote dislike", true, "10.5.0.3", false, 0, 0, nil},
+		{"vote after unvote", false, "10.5.0.3", true, 1, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vote := exact.VoteComment
+			if tt.unvote {
+				vote = exact.UnvoteComment
+			}
+			likes, dislikes, err := vote(ctx, c.ID, tt.voter, tt.up)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("vote error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if likes != tt.wantLikes || dislikes != tt.wantDislikes {
+				t.Errorf("vote = %d, %d, want %d, %d", likes, dislikes, tt.wantLikes, tt.wantDislikes)
+			}
+		})
+	}
+
+	// exact voters are in the bloom filter, they can not vote again without exact votes
+	if _, _, err := db.VoteComment(ctx, c.ID, "10.5.0.3", false); !errors.Is(err, isso.ErrAlreadyVoted) {
+		t.Errorf("Database.VoteComment() of exact voter error = %v, want %v", err, isso.ErrAlreadyVoted)
+	}
+
+	// voters are hashed with the voter key, a hash of the address alone can be brute-forced
+	key, err := db.GetPreference(isso.VoterKeyPreference)
+	if err != nil {
+		t.Fatalf("Database.GetPreference() error = %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("10.5.0.3"))
+	var voter string
+	if err := db.DB.QueryRow(fmt.Sprintf(`SELECT voter FROM votes WHERE comment_id=%d AND value=1`, c.ID)).Scan(&voter); err != nil ||
+		voter != hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("voter in votes = %q, %v, want the HMAC of the address", voter, err)
+	}
+
+	// no limit of the bloom filter
+	for i := 0; i < isso.MaxVotes+10; i++ {
+		if _, _, err := exact.VoteComment(ctx, c.ID, fmt.Sprintf("10.6.%d.%d", i/256, i%256), false); err != nil {
+			t.Fatalf("Database.VoteComment() error = %v", err)
+		}
+	}
+	if _, _, err := db.UnvoteComment(ctx, c.ID, "10.5.0.3", true); !errors.Is(err, isso.ErrNotSupported) {
+		t.Errorf("Database.UnvoteComment() without exact votes error = %v, want %v", err, isso.ErrNotSupported)
+	}
+
+	if _, err := exact.DeleteComment(ctx, c.ID); err != nil {
+		t.Fatalf("Database.DeleteComment() error = %v", err)
+	}
+	var n int
+	if err := db.DB.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM votes WHERE comment_id=%d`, c.ID)).Scan(&n); err != nil || n != 0 {
+		t.Errorf("votes of deleted comment = %d, %v, want 0", n, err)
+	}
+}
@@ -0,0 +1,223 @@
+// Package exporter write threads and comments in isso.Storage out of go-isso.
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/version"
+)
+
+// JSONVersion is the version of the document written by JSON.
+// It must be increased when a field changes its meaning.
+// Version 2 adds exact votes and reactions.
+const JSONVersion = 2
+
+// record types of the JSON document
+const (
+	RecordHeader     = "header"
+	RecordThread     = "thread"
+	RecordComment    = "comment"
+	RecordVote       = "vote"
+	RecordReaction   = "reaction"
+	RecordPreference = "preference"
+)
+
+// SecretPreferences are preferences never exported, they are keys generated by isso.New
+// and must stay unique for every installation. isso.VoterKeyPreference is exported,
+// the voters of votes and reactions are only recognized with it.
+var SecretPreferences = map[string]bool{
+	isso.HashKeyPreference:  true,
+	isso.BlockKeyPreference: true,
+}
+
+// Record is one line of the newline delimited JSON document.
+// The first record is always the header, then threads, comments, votes and reactions
+// of every thread, and preferences.
+type Record struct {
+	Type       string      `json:"type"`
+	Header     *Header     `json:"header,omitempty"`
+	Thread     *Thread     `json:"thread,omitempty"`
+	Comment    *Comment    `json:"comment,omitempty"`
+	Vote       *Vote       `json:"vote,omitempty"`
+	Reaction   *Reaction   `json:"reaction,omitempty"`
+	Preference *Preference `json:"preference,omitempty"`
+}
+
+// Header describe the document.
+type Header struct {
+	Version   int     `json:"version"`
+	Generator string  `json:"generator"`
+	Created   float64 `json:"created"`
+}
+
+// Thread is the exported isso.Thread
+type Thread struct {
+	ID    int64  `json:"id"`
+	URI   string `json:"uri"`
+	Title string `json:"title"`
+}
+
+// Comment is the exported isso.Comment, nothing is left out.
+type Comment struct {
+	TID          int64    `json:"tid"`
+	ID           int64    `json:"id"`
+	Parent       *int64   `json:"parent"`
+	Created      float64  `json:"created"`
+	Modified     *float64 `json:"modified"`
+	Mode         int      `json:"mode"`
+	RemoteAddr   string   `json:"remote_addr"`
+	Text         string   `json:"text"`
+	Author       string   `json:"author"`
+	Email        *string  `json:"email"`
+	Website      *string  `json:"website"`
+	Likes        int      `json:"likes"`
+	Dislikes     int      `json:"dislikes"`
+	Voters       []byte   `json:"voters"`
+	Notification int      `json:"notification"`
+}
+
+// Vote is the exported isso.Vote of a comment of thread tid.
+type Vote struct {
+	TID       int64   `json:"tid"`
+	CommentID int64   `json:"comment_id"`
+	Voter     string  `json:"voter"`
+	Value     int     `json:"value"`
+	Created   float64 `json:"created"`
+}
+
+// Reaction is the exported isso.Reaction of a comment of thread tid.
+type Reaction struct {
+	TID       int64   `json:"tid"`
+	CommentID int64   `json:"comment_id"`
+	Voter     string  `json:"voter"`
+	Reaction  string  `json:"reaction"`
+	Created   float64 `json:"created"`
+}
+
+// Preference is a key value pair in preferences
+type Preference struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// NewComment convert isso.Comment of thread tid.
+func NewComment(c isso.Comment, tid int64) Comment {
+	return Comment{
+		TID:          tid,
+		ID:           c.ID,
+		Parent:       c.Parent,
+		Created:      c.Created,
+		Modified:     c.Modified,
+		Mode:         c.Mode,
+		RemoteAddr:   c.RemoteAddr,
+		Text:         c.Text,
+		Author:       c.Author,
+		Email:        c.Email,
+		Website:      c.Website,
+		Likes:        c.Likes,
+		Dislikes:     c.Dislikes,
+		Voters:       c.Voters[:],
+		Notification: c.Notification,
+	}
+}
+
+// ToComment convert back to isso.Comment
+func (c Comment) ToComment() isso.Comment {
+	comment := isso.Comment{
+		ID:           c.ID,
+		Parent:       c.Parent,
+		Created:      c.Created,
+		Modified:     c.Modified,
+		Mode:         c.Mode,
+		RemoteAddr:   c.RemoteAddr,
+		Text:         c.Text,
+		Author:       c.Author,
+		Email:        c.Email,
+		Website:      c.Website,
+		Likes:        c.Likes,
+		Dislikes:     c.Dislikes,
+		Notification: c.Notification,
+	}
+	copy(comment.Voters[:], c.Voters)
+	return comment
+}
+
+// JSON write everything in storage except SecretPreferences as newline delimited JSON.
+func JSON(ctx context.Context, storage isso.Storage, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	err := encoder.Encode(Record{Type: RecordHeader, Header: &Header{
+		Version:   JSONVersion,
+		Generator: "go-isso " + version.Version,
+		Created:   float64(time.Now().UnixNano()) / float64(1e9),
+	}})
+	if err != nil {
+		return fmt.Errorf("export json: %w", err)
+	}
+
+	threads, err := storage.ListThreads(ctx)
+	if err != nil {
+		return fmt.Errorf("export json: %w", err)
+	}
+	for _, t := range threads {
+		if err := encoder.Encode(Record{Type: RecordThread, Thread: &Thread{t.ID, t.URI, t.Title}}); err != nil {
+			return fmt.Errorf("export json: %w", err)
+		}
+	}
+	for _, t := range threads {
+		comments, err := storage.ListComments(ctx, t.ID)
+		if err != nil {
+			return fmt.Errorf("export json: %w", err)
+		}
+		for _, c := range comments {
+			ec := NewComment(c, t.ID)
+			if err := encoder.Encode(Record{Type: RecordComment, Comment: &ec}); err != nil {
+				return fmt.Errorf("export json: %w", err)
+			}
+		}
+		votes, err := storage.ListVotes(ctx, t.ID)
+		if err != nil {
+			return fmt.Errorf("export json: %w", err)
+		}
+		for _, v := range votes {
+			ev := Vote{t.ID, v.CommentID, v.Voter, v.Value, v.Created}
+			if err := encoder.Encode(Record{Type: RecordVote, Vote: &ev}); err != nil {
+				return fmt.Errorf("export json: %w", err)
+			}
+		}
+		reactions, err := storage.ListReactions(ctx, t.ID)
+		if err != nil {
+			return fmt.Errorf("export json: %w", err)
+		}
+		for _, r := range reactions {
+			er := Reaction{t.ID, r.CommentID, r.Voter, r.Reaction, r.Created}
+			if err := encoder.Encode(Record{Type: RecordReaction, Reaction: &er}); err != nil {
+				return fmt.Errorf("export json: %w", err)
+			}
+		}
+	}
+
+	preferences, err := storage.ListPreferences()
+	if err != nil {
+		return fmt.Errorf("export json: %w", err)
+	}
+	keys := make([]string, 0, len(preferences))
+	for key := range preferences {
+		if !SecretPreferences[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		err := encoder.Encode(Record{Type: RecordPreference, Preference: &Preference{key, preferences[key]}})
+		if err != nil {
+			return fmt.Errorf("export json: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package exporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// StaticOptions control how Static write files.
+type StaticOptions struct {
+	// Out is the directory files are written to.
+	Out string
+	// HTML also write a HTML fragment next to every JSON file.
+	HTML bool
+}
+
+// StaticThread is an entry of the index written by Static.
+type StaticThread struct {
+	URI   string `json:"uri"`
+	Title string `json:"title"`
+	Count int64  `json:"count"`
+	// File is the path of the JSON file relative to the output directory.
+	File string `json:"file"`
+}
+
+const (
+	staticIndexFile = "index.json"
+	staticJSONFile  = "comments.json"
+	staticHTMLFile  = "comments.html"
+	// staticThreadsDir hold the directories of threads, apart from the index
+	staticThreadsDir = "threads"
+	// staticSlugMax is the length limit of the readable part of a thread directory
+	staticSlugMax = 64
+)
+
+var staticTemplate = template.Must(template.New("comments").Funcs(template.FuncMap{
+	"datetime": func(t float64) string {
+		return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+	},
+	"trusted": func(s string) template.HTML {
+		return template.HTML(s)
+	},
+	"deleted": func(mode int) bool {
+		return mode == isso.ModeDeleted
+	},
+}).Parse(`<div class="isso-static-comments">
+{{- range .Replies}}{{template "comment" .}}{{end}}
+</div>
+{{define "comment"}}
+<div class="isso-comment" id="isso-{{.ID}}">
+	<div class="isso-text-wrapper">
+		<div role="meta" class="isso-comment-header">
+			{{- if .Website}}<a class="author" href="{{.Website}}" rel="nofollow">{{.Author}}</a>
+			{{- else}}<span class="author">{{.Author}}</span>{{end}}
+			<span class="spacer">&bull;</span>
+			<time datetime="{{datetime .Created}}">{{datetime .Created}}</time>
+		</div>
+		<div class="isso-text">
+			{{- if deleted .Mode}}<p class="deleted">Comment deleted.</p>{{else}}{{trusted .Text}}{{end -}}
+		</div>
+	</div>
+	{{- with .Replies}}
+	<div class="isso-follow-up">
+		{{- range .}}{{template "comment" .}}{{end}}
+	</div>
+	{{- end}}
+</div>
+{{- end}}`))
+
+// Static write the public comments of every thread as Fetch return them,
+// one directory per thread uri, see staticDir, and an index with their counts.
+func Static(ctx context.Context, storage isso.Storage, renderer *isso.ISSO, opts StaticOptions) ([]StaticThread, error) {
+	threads, err := storage.ListThreads(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("export static: %w", err)
+	}
+
+	index := make([]StaticThread, 0, len(threads))
+	for _, t := range threads {
+		result, err := renderer.Fetch(ctx, t.URI, isso.FetchParam{})
+		if err != nil {
+			return nil, fmt.Errorf("export static: fetch %s failed: %w", t.URI, err)
+		}
+		count := result.TotalReplies
+		for _, r := range result.Replies {
+			if r.TotalReplies != nil {
+				count += *r.TotalReplies
+			}
+		}
+
+		dir := staticDir(t.URI)
+		if err := os.MkdirAll(filepath.Join(opts.Out, dir), 0755); err != nil {
+			return nil, fmt.Errorf("export static: %w", err)
+		}
+		file := path.Join(filepath.ToSlash(dir), staticJSONFile)
+		if err := writeJSONFile(filepath.Join(opts.Out, file), result); err != nil {
+			return nil, fmt.Errorf("export static: %w", err)
+		}
+		if opts.HTML {
+			if err := writeHTMLFile(filepath.Join(opts.Out, dir, staticHTMLFile), result); err != nil {
+				return nil, fmt.Errorf("export static: %w", err)
+			}
+		}
+		index = append(index, StaticThread{URI: t.URI, Title: t.Title, Count: count, File: file})
+	}
+
+	if err := writeJSONFile(filepath.Join(opts.Out, staticIndexFile), index); err != nil {
+		return nil, fmt.Errorf("export static: %w", err)
+	}
+	return index, nil
+}
+
+// staticDir map a thread uri to a directory relative to the output directory, like
+// threads/posts_hello-<hash> for /posts/hello/. The readable part is for humans only,
+// distinct uris get distinct directories by the hash of the whole uri, even on
+// case-insensitive file systems. It never escapes the threads directory.
+func staticDir(uri string) string {
+	slug := strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == '_' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, uri)
+	slug = strings.Trim(slug, "_.")
+	if len(slug) > staticSlugMax {
+		slug = slug[:staticSlugMax]
+	}
+	sum := sha256.Sum256([]byte(uri))
+	name := hex.EncodeToString(sum[:8])
+	if slug != "" {
+		name = slug + "-" + name
+	}
+	return filepath.Join(staticThreadsDir, name)
+}
+
+func writeJSONFile(name string, v interface{}) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeHTMLFile(name string, result isso.FetchResult) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := staticTemplate.Execute(f, result); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
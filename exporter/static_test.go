@@ -0,0 +1,127 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"wrong.wang/x/go-isso/config"
+	"wrong.wang/x/go-isso/database"
+	"wrong.wang/x/go-isso/isso"
+)
+
+func TestStatic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-isso-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	storage, err := database.New(filepath.Join(dir, "go-isso.db"), 1*time.Second)
+	if err != nil {
+		t.Fatalf("init database failed: %v", err)
+	}
+	defer storage.Close()
+	thread, err := storage.NewThread(ctx, "/posts/hello/", "Hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := "alice@example.com"
+	first, err := storage.NewComment(ctx, isso.Comment{Text: "**first**", Author: "alice", Email: &email,
+		Mode: isso.ModeAccepted}, thread.ID, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []isso.Comment{
+		{Text: "reply", Author: "bob", Parent: &first.ID, Mode: isso.ModeAccepted},
+		{Text: "waiting", Author: "carol", Mode: isso.ModeModeration},
+	} {
+		if _, err := storage.NewComment(ctx, c, thread.ID, "10.0.0.2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	preferences, err := storage.ListPreferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	index, err := Static(ctx, storage, isso.NewRenderer(config.Config{}, storage), StaticOptions{Out: out, HTML: true})
+	if err != nil {
+		t.Fatalf("Static() error = %v", err)
+	}
+	// exporting is read only
+	if got, err := storage.ListPreferences(); err != nil || !reflect.DeepEqual(got, preferences) {
+		t.Errorf("Static() changed preferences to %v, %v, want %v", got, err, preferences)
+	}
+	want := []StaticThread{{URI: "/posts/hello/", Title: "Hello", Count: 2,
+		File: filepath.ToSlash(filepath.Join(staticDir("/posts/hello/"), "comments.json"))}}
+	if len(index) != 1 || index[0] != want[0] {
+		t.Errorf("Static() = %+v, want %+v", index, want)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(out, filepath.FromSlash(want[0].File)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result isso.FetchResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("comments.json is not valid: %v", err)
+	}
+	if result.TotalReplies != 1 || len(result.Replies) != 1 || *result.Replies[0].TotalReplies != 1 {
+		t.Errorf("comments.json = %s", data)
+	}
+	if strings.Contains(string(data), email) || strings.Contains(string(data), "waiting") {
+		t.Errorf("comments.json leak private data: %s", data)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(out, staticDir("/posts/hello/"), "comments.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<strong>first</strong>") || !strings.Contains(string(data), "isso-follow-up") {
+		t.Errorf("comments.html = %s", data)
+	}
+	if _, err := os.Stat(filepath.Join(out, "index.json")); err != nil {
+		t.Errorf("index.json not written: %v", err)
+	}
+}
+
+func Test_staticDir(t *testing.T) {
+	tests := []struct {
+		uri    string
+		prefix string
+	}{
+		{"/", filepath.Join("threads", "")},
+		{"/posts/hello/", filepath.Join("threads", "posts_hello-")},
+		{"/?p=12", filepath.Join("threads", "p_12-")},
+		{"/../../etc/", filepath.Join("threads", "etc-")},
+		{"/a?b", filepath.Join("threads", "a_b-")},
+		{"/a_b", filepath.Join("threads", "a_b-")},
+		{"/A_b", filepath.Join("threads", "A_b-")},
+		{"/posts/hello", filepath.Join("threads", "posts_hello-")},
+		{"/" + strings.Repeat("x", 100), filepath.Join("threads", strings.Repeat("x", 64)+"-")},
+	}
+	dirs := map[string]string{}
+	for _, tt := range tests {
+		got := staticDir(tt.uri)
+		if !strings.HasPrefix(got, tt.prefix) {
+			t.Errorf("staticDir(%q) = %q, want prefix %q", tt.uri, got, tt.prefix)
+		}
+		// one directory in threads, never the output directory or one out of it
+		if filepath.Dir(got) != "threads" || strings.Contains(got, "..") {
+			t.Errorf("staticDir(%q) = %q, escapes the threads directory", tt.uri, got)
+		}
+		if other, ok := dirs[strings.ToLower(got)]; ok {
+			t.Errorf("staticDir(%q) = staticDir(%q) = %q", tt.uri, other, got)
+		}
+		dirs[strings.ToLower(got)] = tt.uri
+	}
+}
@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+type disqusRef struct {
+	ID string `xml:"http://disqus.com/disqus-internals id,attr"`
+}
+
+type disqusThread struct {
+	disqusRef
+	Identifier string `xml:"id"`
+	Link       string `xml:"link"`
+	Title      string `xml:"title"`
+}
+
+type disqusPost struct {
+	disqusRef
+	Message   string `xml:"message"`
+	CreatedAt string `xml:"createdAt"`
+	IsDeleted bool   `xml:"isDeleted"`
+	IsSpam    bool   `xml:"isSpam"`
+	Author    struct {
+		Name  string `xml:"name"`
+		Email string `xml:"email"`
+	} `xml:"author"`
+	IPAddress string    `xml:"ipAddress"`
+	Thread    disqusRef `xml:"thread"`
+	Parent    disqusRef `xml:"parent"`
+}
+
+// FromDisqus import a Disqus XML export.
+// Threads are found by their rewritten link, so running it again will not
+// duplicate threads or comments. go-isso only nests replies one level deep,
+// so a reply to a reply is attached to the top comment of its conversation.
+func FromDisqus(ctx context.Context, storage isso.Storage, path string, opts Options) (Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("import disqus: %w", err)
+	}
+	defer f.Close()
+
+	di := newThreadedImporter(ctx, storage, opts)
+	decoder := xml.NewDecoder(f)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return di.result, fmt.Errorf("import disqus: parse xml failed: %w", err)
+		}
+		se, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "disqus":
+			continue
+		case "thread":
+			var t disqusThread
+			if err := decoder.DecodeElement(&t, &se); err != nil {
+				return di.result, fmt.Errorf("import disqus: parse thread failed: %w", err)
+			}
+			di.addThread(t.ID, disqusLink(t), t.Title)
+		case "post":
+			var p disqusPost
+			if err := decoder.DecodeElement(&p, &se); err != nil {
+				return di.result, fmt.Errorf("import disqus: parse post failed: %w", err)
+			}
+			c, err := disqusComment(p)
+			if err != nil {
+				return di.result, fmt.Errorf("import disqus: post %s: %w", p.ID, err)
+			}
+			if err := di.add(c); err != nil {
+				return di.result, fmt.Errorf("import disqus: post %s: %w", p.ID, err)
+			}
+		default:
+			if err := decoder.Skip(); err != nil {
+				return di.result, fmt.Errorf("import disqus: parse xml failed: %w", err)
+			}
+		}
+	}
+	if err := di.flush(); err != nil {
+		return di.result, fmt.Errorf("import disqus: %w", err)
+	}
+	return di.result, nil
+}
+
+func disqusLink(t disqusThread) string {
+	if t.Link != "" {
+		return t.Link
+	}
+	return t.Identifier
+}
+
+func disqusComment(p disqusPost) (foreignComment, error) {
+	created, err := time.Parse(time.RFC3339, p.CreatedAt)
+	if err != nil {
+		return foreignComment{}, err
+	}
+	c := foreignComment{
+		id:       p.ID,
+		threadID: p.Thread.ID,
+		parentID: p.Parent.ID,
+	}
+	c.Created = float64(created.Unix())
+	c.Mode = isso.ModeAccepted
+	c.RemoteAddr = p.IPAddress
+	c.Text = htmlToMarkdown(p.Message)
+	c.Author = p.Author.Name
+	if p.Author.Email != "" {
+		email := p.Author.Email
+		c.Email = &email
+	}
+	switch {
+	case p.IsDeleted:
+		c.Mode = isso.ModeDeleted
+		c.Text, c.Author, c.Email, c.Website = "", "", nil, nil
+	case p.IsSpam:
+		c.Mode = isso.ModeModeration
+	}
+	return c, nil
+}
@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func TestFromDisqus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-isso-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	storage := newStorage(t, dir)
+	defer storage.Close()
+	ctx := context.Background()
+
+	t.Run("dry run", func(t *testing.T) {
+		result, err := FromDisqus(ctx, storage, "testdata/disqus.xml", Options{DryRun: true})
+		if err != nil {
+			t.Fatalf("FromDisqus() error = %v", err)
+		}
+		if want := (Result{Threads: 1, Comments: 6, Skipped: 1}); result != want {
+			t.Errorf("FromDisqus() = %v, want %v", result, want)
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		result, err := FromDisqus(ctx, storage, "testdata/disqus.xml", Options{})
+		if err != nil {
+			t.Fatalf("FromDisqus() error = %v", err)
+		}
+		if want := (Result{Threads: 1, Comments: 6, Skipped: 1}); result != want {
+			t.Errorf("FromDisqus() = %v, want %v", result, want)
+		}
+		if _, err := storage.GetThreadByURI(ctx, "/posts/empty/"); err == nil {
+			t.Errorf("thread without comment should not be created")
+		}
+		thread, err := storage.GetThreadByURI(ctx, "/posts/hello/")
+		if err != nil || thread.Title != "Hello World" {
+			t.Fatalf("GetThreadByURI() = %v, %v", thread, err)
+		}
+		commentsByParent, err := storage.FetchCommentsByURI(ctx, thread.URI, -1,
+			isso.ModeAccepted|isso.ModeModeration|isso.ModeDeleted, "id", true)
+		if err != nil {
+			t.Fatalf("FetchCommentsByURI() error = %v", err)
+		}
+		top := commentsByParent[0]
+		if len(top) != 3 {
+			t.Fatalf("want 3 top comments, got %+v", top)
+		}
+		first := top[0]
+		if first.Text != "First **post**\n\nsee [this](https://example.org)" ||
+			first.Author != "Alice" || first.Email == nil || first.RemoteAddr != "10.0.0.1" ||
+			first.Created != 1381572000 || first.Mode != isso.ModeAccepted {
+			t.Errorf("first comment = %+v", first)
+		}
+		if top[1].Mode != isso.ModeModeration {
+			t.Errorf("spam comment should wait for moderation, got mode %d", top[1].Mode)
+		}
+		replies := commentsByParent[first.ID]
+		if len(replies) != 2 {
+			t.Fatalf("want 2 replies, got %+v", replies)
+		}
+		// the replies of Carol share author, address and second, they differ in text only,
+		// the deleted reply they replied to is not imported
+		if replies[0].Author != "Carol" || replies[1].Author != "Carol" || replies[1].Text != "and another one" {
+			t.Errorf("replies = %+v", replies)
+		}
+		// the deleted top comment is kept for its reply
+		deleted := top[2]
+		if deleted.Mode != isso.ModeDeleted || deleted.Text != "" || deleted.Author != "" || deleted.Email != nil {
+			t.Errorf("deleted comment = %+v", deleted)
+		}
+		if replies := commentsByParent[deleted.ID]; len(replies) != 1 || replies[0].Author != "Dave" {
+			t.Errorf("replies of deleted comment = %+v", replies)
+		}
+		checkConsistency(t, storage)
+	})
+
+	t.Run("again", func(t *testing.T) {
+		result, err := FromDisqus(ctx, storage, "testdata/disqus.xml", Options{})
+		if err != nil {
+			t.Fatalf("FromDisqus() error = %v", err)
+		}
+		if want := (Result{Skipped: 7}); result != want {
+			t.Errorf("FromDisqus() = %v, want %v", result, want)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlToMarkdown convert the html of exported comments back into markdown,
+// unknown elements are kept as their text.
+func htmlToMarkdown(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return strings.TrimSpace(s)
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{
+		Type: html.ElementNode, Data: "body", DataAtom: atom.Body,
+	})
+	if err != nil {
+		return strings.TrimSpace(s)
+	}
+	var b strings.Builder
+	for _, n := range nodes {
+		writeMarkdown(&b, n)
+	}
+	lines := strings.Split(b.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	text := strings.Join(lines, "\n")
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.Replace(text, "\n\n\n", "\n\n", -1)
+	}
+	return strings.TrimSpace(text)
+}
+
+func writeMarkdown(w io.StringWriter, n *html.Node) {
+	children := func() {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeMarkdown(w, c)
+		}
+	}
+	if n.Type == html.TextNode {
+		w.WriteString(n.Data)
+		return
+	}
+	if n.Type != html.ElementNode {
+		children()
+		return
+	}
+	switch n.Data {
+	case "br":
+		w.WriteString("  \n")
+	case "p", "div":
+		children()
+		w.WriteString("\n\n")
+	case "b", "strong":
+		w.WriteString("**")
+		children()
+		w.WriteString("**")
+	case "i", "em":
+		w.WriteString("*")
+		children()
+		w.WriteString("*")
+	case "code":
+		w.WriteString("`")
+		children()
+		w.WriteString("`")
+	case "blockquote":
+		var b strings.Builder
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeMarkdown(&b, c)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+			w.WriteString("> " + line + "\n")
+		}
+		w.WriteString("\n")
+	case "a":
+		href, _ := getAttr(n, "href")
+		if href == "" {
+			children()
+			return
+		}
+		w.WriteString("[")
+		children()
+		w.WriteString(fmt.Sprintf("](%s)", href))
+	default:
+		children()
+	}
+}
+
+func getAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
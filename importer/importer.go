@@ -0,0 +1,60 @@
+// Package importer load threads and comments from other comment systems into isso.Storage.
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Options control how an import run.
+type Options struct {
+	// DryRun only read and count the source, nothing will be written into storage.
+	DryRun bool
+	// Rewrite is applied to the link of every thread before it is saved.
+	Rewrite URIRewriter
+}
+
+// URIRewriter turn the link of exported pages into thread uri.
+type URIRewriter struct {
+	// KeepDomain keep scheme and host of absolute links, they are stripped by default.
+	KeepDomain bool
+	// OldPrefix at the beginning of the path is replaced with NewPrefix.
+	OldPrefix string
+	NewPrefix string
+}
+
+// Rewrite return the thread uri of link, the path of a front page like https://example.com is /.
+func (r URIRewriter) Rewrite(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	if r.OldPrefix != "" && strings.HasPrefix(u.Path, r.OldPrefix) {
+		u.Path = r.NewPrefix + strings.TrimPrefix(u.Path, r.OldPrefix)
+		u.RawPath = ""
+	}
+	if u.Path == "" && u.Opaque == "" {
+		u.Path = "/"
+	}
+	if !r.KeepDomain {
+		u.Scheme, u.Opaque, u.User, u.Host = "", "", nil, ""
+	}
+	return u.String()
+}
+
+// Result count what an import wrote, or would write when DryRun is set.
+type Result struct {
+	Threads     int
+	Comments    int
+	Votes       int
+	Reactions   int
+	Preferences int
+	// Skipped count the threads, comments, votes, reactions and preferences already exist in storage.
+	Skipped int
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%d threads, %d comments, %d votes, %d reactions, %d preferences, %d skipped",
+		r.Threads, r.Comments, r.Votes, r.Reactions, r.Preferences, r.Skipped)
+}
@@ -0,0 +1,226 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	// sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+	"gopkg.in/guregu/null.v4"
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/logger"
+)
+
+// issoSecretPreferences are preferences only meaningful for the python isso.
+var issoSecretPreferences = map[string]bool{
+	"session-key": true,
+}
+
+// ErrIDExists is returned by FromISSO when an id of the database is taken in storage by
+// another thread or comment.
+var ErrIDExists = errors.New("import isso: id exists in storage")
+
+// issoComment is a comment of the python isso with its thread id.
+type issoComment struct {
+	isso.Comment
+	tid int64
+}
+
+// FromISSO import a SQLite database created by the python isso.
+// ids and parent links are kept as they are.
+// Threads whose uri already exist in storage are skipped together with their comments.
+// Nothing is written if an id of the other threads or comments exists in storage,
+// otherwise everything is written in one transaction.
+func FromISSO(ctx context.Context, storage isso.Storage, path string, opts Options) (Result, error) {
+	var result Result
+	if _, err := os.Stat(path); err != nil {
+		return result, fmt.Errorf("import isso: %w", err)
+	}
+	src, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", path))
+	if err != nil {
+		return result, fmt.Errorf("import isso: open %s failed: %w", path, err)
+	}
+	defer src.Close()
+
+	threads, skippedThreads, err := issoThreads(ctx, src, storage, &result)
+	if err != nil {
+		return result, fmt.Errorf("import isso: %w", err)
+	}
+	comments, err := issoComments(ctx, src, storage, skippedThreads, &result)
+	if err != nil {
+		return result, fmt.Errorf("import isso: %w", err)
+	}
+	preferences, err := issoPreferences(ctx, src, storage, &result)
+	if err != nil {
+		return result, fmt.Errorf("import isso: %w", err)
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	err = storage.WithTx(ctx, func(tx isso.Storage) error {
+		for _, t := range threads {
+			if _, err := tx.RestoreThread(ctx, t); err != nil {
+				return fmt.Errorf("thread %s: %w", t.URI, err)
+			}
+		}
+		// ordered by id, parents are restored before their replies
+		for _, c := range comments {
+			if _, err := tx.RestoreComment(ctx, c.Comment, c.tid); err != nil {
+				return fmt.Errorf("comment %d: %w", c.ID, err)
+			}
+		}
+		for key, value := range preferences {
+			if err := tx.SetPreference(key, value); err != nil {
+				return fmt.Errorf("preference %s: %w", key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("import isso: %w", err)
+	}
+	return result, nil
+}
+
+// issoThreads return the threads to import and the ids of the skipped ones.
+func issoThreads(ctx context.Context, src *sql.DB, storage isso.Storage, result *Result) ([]isso.Thread, map[int64]bool, error) {
+	rows, err := src.QueryContext(ctx, `SELECT id, uri, title FROM threads ORDER BY id`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read threads failed: %w", err)
+	}
+	defer rows.Close()
+
+	var threads []isso.Thread
+	skipped := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		var uri, title null.String
+		if err := rows.Scan(&id, &uri, &title); err != nil {
+			return nil, nil, fmt.Errorf("read threads failed: %w", err)
+		}
+		_, err := storage.GetThreadByURI(ctx, uri.String)
+		if err == nil {
+			logger.Info("thread %s already exists, skip it", uri.String)
+			skipped[id] = true
+			result.Skipped++
+			continue
+		}
+		if !errors.Is(err, isso.ErrStorageNotFound) {
+			return nil, nil, err
+		}
+		if _, err := storage.GetThreadByID(ctx, id); err == nil {
+			return nil, nil, fmt.Errorf("%w: thread %d of %s", ErrIDExists, id, uri.String)
+		} else if !errors.Is(err, isso.ErrStorageNotFound) {
+			return nil, nil, err
+		}
+		threads = append(threads, isso.Thread{ID: id, URI: uri.String, Title: title.String})
+		result.Threads++
+	}
+	return threads, skipped, rows.Err()
+}
+
+// issoComments return the comments to import ordered by id.
+func issoComments(ctx context.Context, src *sql.DB, storage isso.Storage, skippedThreads map[int64]bool, result *Result) ([]issoComment, error) {
+	// old isso database may not have field `notification`
+	var hasNotification bool
+	err := src.QueryRowContext(ctx,
+		`SELECT COUNT(*) > 0 FROM pragma_table_info('comments') WHERE name='notification'`).Scan(&hasNotification)
+	if err != nil {
+		return nil, fmt.Errorf("read comments schema failed: %w", err)
+	}
+	notification := "0"
+	if hasNotification {
+		notification = "notification"
+	}
+
+	rows, err := src.QueryContext(ctx, fmt.Sprintf(`SELECT tid, id, parent, created, modified, mode,
+		remote_addr, text, author, email, website, likes, dislikes, voters, %s
+		FROM comments ORDER BY id`, notification))
+	if err != nil {
+		return nil, fmt.Errorf("read comments failed: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []issoComment
+	for rows.Next() {
+		var tid int64
+		var c isso.Comment
+		var parent null.Int
+		var modified null.Float
+		var mode, likes, dislikes, notify null.Int
+		var remoteAddr, text, author, email, website null.String
+		var voters []byte
+		err := rows.Scan(&tid, &c.ID, &parent, &c.Created, &modified, &mode,
+			&remoteAddr, &text, &author, &email, &website, &likes, &dislikes, &voters, &notify)
+		if err != nil {
+			return nil, fmt.Errorf("read comments failed: %w", err)
+		}
+		if skippedThreads[tid] {
+			result.Skipped++
+			continue
+		}
+		if _, err := storage.GetComment(ctx, c.ID); err == nil {
+			return nil, fmt.Errorf("%w: comment %d", ErrIDExists, c.ID)
+		} else if !errors.Is(err, isso.ErrStorageNotFound) {
+			return nil, err
+		}
+		c.Parent = parent.Ptr()
+		c.Modified = modified.Ptr()
+		c.Mode = issoMode(mode.Int64)
+		c.RemoteAddr = remoteAddr.String
+		c.Text = text.String
+		c.Author = author.String
+		c.Email = email.Ptr()
+		c.Website = website.Ptr()
+		c.Likes = int(likes.Int64)
+		c.Dislikes = int(dislikes.Int64)
+		copy(c.Voters[:], voters)
+		if notify.Int64 > 0 {
+			c.Notification = 1
+		}
+		comments = append(comments, issoComment{c, tid})
+		result.Comments++
+	}
+	return comments, rows.Err()
+}
+
+// issoMode map python isso's comment mode, unknown mode will wait for moderation.
+func issoMode(mode int64) int {
+	switch mode {
+	case isso.ModeAccepted, isso.ModeModeration, isso.ModeDeleted:
+		return int(mode)
+	default:
+		return isso.ModeModeration
+	}
+}
+
+// issoPreferences return the preferences not in storage yet.
+func issoPreferences(ctx context.Context, src *sql.DB, storage isso.Storage, result *Result) (map[string]string, error) {
+	rows, err := src.QueryContext(ctx, `SELECT key, value FROM preferences`)
+	if err != nil {
+		return nil, fmt.Errorf("read preferences failed: %w", err)
+	}
+	defer rows.Close()
+
+	preferences := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("read preferences failed: %w", err)
+		}
+		if issoSecretPreferences[key] {
+			continue
+		}
+		if _, err := storage.GetPreference(key); err == nil {
+			result.Skipped++
+			continue
+		}
+		preferences[key] = value
+		result.Preferences++
+	}
+	return preferences, rows.Err()
+}
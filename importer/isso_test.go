@@ -0,0 +1,167 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wrong.wang/x/go-isso/database"
+	"wrong.wang/x/go-isso/isso"
+)
+
+// python isso's schema before field `notification` was added
+const issoSchema = `
+CREATE TABLE threads (id INTEGER PRIMARY KEY, uri VARCHAR(256) UNIQUE, title VARCHAR(256));
+CREATE TABLE preferences (key VARCHAR PRIMARY KEY, value VARCHAR);
+CREATE TABLE comments (
+	tid REFERENCES threads(id), id INTEGER PRIMARY KEY, parent INTEGER,
+	created FLOAT NOT NULL, modified FLOAT, mode INTEGER, remote_addr VARCHAR,
+	text VARCHAR, author VARCHAR, email VARCHAR, website VARCHAR,
+	likes INTEGER DEFAULT 0, dislikes INTEGER DEFAULT 0, voters BLOB NOT NULL);
+INSERT INTO threads VALUES (3, '/hello/', 'Hello'), (7, '/about/', NULL);
+INSERT INTO preferences VALUES ('session-key', 'secret'), ('theme', 'dark');
+INSERT INTO comments VALUES
+	(3, 10, NULL, 1500000000.5, NULL, 1, '127.0.0.0', 'first', 'alice', 'a@example.com', NULL, 2, 1, x'01'),
+	(3, 11, 10, 1500000001.5, 1500000002.5, 4, '127.0.0.0', '', '', NULL, NULL, 0, 0, x'00'),
+	(3, 12, 11, 1500000003.5, NULL, 2, '10.0.0.0', 'third', 'bob', NULL, 'https://b.example', 0, 0, x'00'),
+	(7, 13, NULL, 1500000004.5, NULL, 1, '10.0.0.0', 'about', 'carol', NULL, NULL, 0, 0, x'00');
+`
+
+func newISSODatabase(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "isso.db")
+	src, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("open isso database failed: %v", err)
+	}
+	defer src.Close()
+	if _, err := src.Exec(issoSchema); err != nil {
+		t.Fatalf("create isso database failed: %v", err)
+	}
+	return path
+}
+
+func newStorage(t *testing.T, dir string) *database.Database {
+	storage, err := database.New(filepath.Join(dir, "go-isso.db"), 1*time.Second)
+	if err != nil {
+		t.Fatalf("init database failed: %v", err)
+	}
+	return storage
+}
+
+// checkConsistency fail t if an import leaves storage in a state `go-isso db check` reports.
+func checkConsistency(t *testing.T, storage *database.Database) {
+	t.Helper()
+	ctx := context.Background()
+	if problems, err := storage.IntegrityCheck(ctx); err != nil || len(problems) > 0 {
+		t.Errorf("IntegrityCheck() = %v, %v", problems, err)
+	}
+	if found, err := storage.CheckConsistency(ctx); err != nil || len(found) > 0 {
+		t.Errorf("CheckConsistency() = %+v, %v", found, err)
+	}
+}
+
+func TestFromISSO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-isso-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := newISSODatabase(t, dir)
+	storage := newStorage(t, dir)
+	defer storage.Close()
+	ctx := context.Background()
+
+	t.Run("dry run", func(t *testing.T) {
+		result, err := FromISSO(ctx, storage, path, Options{DryRun: true})
+		if err != nil {
+			t.Fatalf("FromISSO() error = %v", err)
+		}
+		if want := (Result{Threads: 2, Comments: 4, Preferences: 1}); result != want {
+			t.Errorf("FromISSO() = %v, want %v", result, want)
+		}
+		if _, err := storage.GetThreadByID(ctx, 3); err == nil {
+			t.Errorf("dry run should not write thread")
+		}
+	})
+
+	t.Run("import", func(t *testing.T) {
+		result, err := FromISSO(ctx, storage, path, Options{})
+		if err != nil {
+			t.Fatalf("FromISSO() error = %v", err)
+		}
+		if want := (Result{Threads: 2, Comments: 4, Preferences: 1}); result != want {
+			t.Errorf("FromISSO() = %v, want %v", result, want)
+		}
+
+		thread, err := storage.GetThreadByID(ctx, 3)
+		if err != nil || thread.URI != "/hello/" || thread.Title != "Hello" {
+			t.Errorf("GetThreadByID() = %v, %v", thread, err)
+		}
+		c, err := storage.GetComment(ctx, 12)
+		if err != nil {
+			t.Fatalf("GetComment() error = %v", err)
+		}
+		if c.Parent == nil || *c.Parent != 11 || c.Mode != isso.ModeModeration || c.Author != "bob" {
+			t.Errorf("GetComment() = %+v, parent or fields not kept", c)
+		}
+		c, err = storage.GetComment(ctx, 10)
+		if err != nil {
+			t.Fatalf("GetComment() error = %v", err)
+		}
+		if c.Likes != 2 || c.Dislikes != 1 || c.Voters[0] != 1 || c.Created != 1500000000.5 {
+			t.Errorf("GetComment() = %+v, votes or created not kept", c)
+		}
+		if v, err := storage.GetPreference("theme"); err != nil || v != "dark" {
+			t.Errorf("GetPreference() = %s, %v", v, err)
+		}
+		if _, err := storage.GetPreference("session-key"); err == nil {
+			t.Errorf("session-key should not be imported")
+		}
+		checkConsistency(t, storage)
+	})
+
+	t.Run("again", func(t *testing.T) {
+		result, err := FromISSO(ctx, storage, path, Options{})
+		if err != nil {
+			t.Fatalf("FromISSO() error = %v", err)
+		}
+		if want := (Result{Skipped: 7}); result != want {
+			t.Errorf("FromISSO() = %v, want %v", result, want)
+		}
+	})
+}
+
+func TestFromISSO_idExists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-isso-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := newISSODatabase(t, dir)
+	storage := newStorage(t, dir)
+	defer storage.Close()
+	ctx := context.Background()
+
+	if _, err := storage.RestoreThread(ctx, isso.Thread{ID: 1, URI: "/other/", Title: "Other"}); err != nil {
+		t.Fatalf("RestoreThread() error = %v", err)
+	}
+	c := isso.Comment{ID: 12, Created: 1500000000.5, Mode: isso.ModeAccepted, RemoteAddr: "10.0.0.1", Text: "taken", Author: "dave"}
+	if _, err := storage.RestoreComment(ctx, c, 1); err != nil {
+		t.Fatalf("RestoreComment() error = %v", err)
+	}
+
+	if _, err := FromISSO(ctx, storage, path, Options{}); !errors.Is(err, ErrIDExists) {
+		t.Fatalf("FromISSO() error = %v, want %v", err, ErrIDExists)
+	}
+	if _, err := storage.GetThreadByID(ctx, 3); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("GetThreadByID() error = %v, nothing should be imported", err)
+	}
+	if _, err := storage.GetPreference("theme"); err == nil {
+		t.Errorf("preference should not be imported")
+	}
+}
@@ -0,0 +1,128 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"wrong.wang/x/go-isso/exporter"
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/logger"
+)
+
+// FromJSON restore a document written by exporter.JSON, ids are kept as they are.
+// Threads whose uri already exist in storage are skipped together with their comments,
+// votes and reactions.
+func FromJSON(ctx context.Context, storage isso.Storage, path string, opts Options) (Result, error) {
+	var result Result
+	f, err := os.Open(path)
+	if err != nil {
+		return result, fmt.Errorf("import json: %w", err)
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	var header exporter.Record
+	if err := decoder.Decode(&header); err != nil {
+		return result, fmt.Errorf("import json: read header failed: %w", err)
+	}
+	if header.Type != exporter.RecordHeader || header.Header == nil {
+		return result, errors.New("import json: document must start with a header")
+	}
+	if header.Header.Version > exporter.JSONVersion {
+		return result, fmt.Errorf("import json: document version %d is newer than supported %d",
+			header.Header.Version, exporter.JSONVersion)
+	}
+
+	skippedThreads := map[int64]bool{}
+	for {
+		var r exporter.Record
+		err := decoder.Decode(&r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("import json: %w", err)
+		}
+		switch {
+		case r.Type == exporter.RecordThread && r.Thread != nil:
+			_, err := storage.GetThreadByURI(ctx, r.Thread.URI)
+			if err == nil {
+				logger.Info("thread %s already exists, skip it", r.Thread.URI)
+				skippedThreads[r.Thread.ID] = true
+				result.Skipped++
+				continue
+			}
+			if !errors.Is(err, isso.ErrStorageNotFound) {
+				return result, fmt.Errorf("import json: %w", err)
+			}
+			if !opts.DryRun {
+				t := isso.Thread{ID: r.Thread.ID, URI: r.Thread.URI, Title: r.Thread.Title}
+				if _, err := storage.RestoreThread(ctx, t); err != nil {
+					return result, fmt.Errorf("import json: %w", err)
+				}
+			}
+			result.Threads++
+		case r.Type == exporter.RecordComment && r.Comment != nil:
+			if skippedThreads[r.Comment.TID] {
+				result.Skipped++
+				continue
+			}
+			if !opts.DryRun {
+				if _, err := storage.RestoreComment(ctx, r.Comment.ToComment(), r.Comment.TID); err != nil {
+					return result, fmt.Errorf("import json: %w", err)
+				}
+			}
+			result.Comments++
+		case r.Type == exporter.RecordVote && r.Vote != nil:
+			if skippedThreads[r.Vote.TID] {
+				result.Skipped++
+				continue
+			}
+			if !opts.DryRun {
+				v := isso.Vote{CommentID: r.Vote.CommentID, Voter: r.Vote.Voter, Value: r.Vote.Value, Created: r.Vote.Created}
+				if err := storage.RestoreVote(ctx, v); err != nil {
+					return result, fmt.Errorf("import json: %w", err)
+				}
+			}
+			result.Votes++
+		case r.Type == exporter.RecordReaction && r.Reaction != nil:
+			if skippedThreads[r.Reaction.TID] {
+				result.Skipped++
+				continue
+			}
+			if !opts.DryRun {
+				reaction := isso.Reaction{CommentID: r.Reaction.CommentID, Voter: r.Reaction.Voter,
+					Reaction: r.Reaction.Reaction, Created: r.Reaction.Created}
+				if err := storage.RestoreReaction(ctx, reaction); err != nil {
+					return result, fmt.Errorf("import json: %w", err)
+				}
+			}
+			result.Reactions++
+		case r.Type == exporter.RecordPreference && r.Preference != nil:
+			if exporter.SecretPreferences[r.Preference.Key] {
+				continue
+			}
+			if value, err := storage.GetPreference(r.Preference.Key); err == nil {
+				if r.Preference.Key == isso.VoterKeyPreference && value != r.Preference.Value &&
+					result.Votes+result.Reactions > 0 {
+					logger.Info("storage has another %s, imported voters will not be recognized", isso.VoterKeyPreference)
+				}
+				result.Skipped++
+				continue
+			}
+			if !opts.DryRun {
+				if err := storage.SetPreference(r.Preference.Key, r.Preference.Value); err != nil {
+					return result, fmt.Errorf("import json: %w", err)
+				}
+			}
+			result.Preferences++
+		default:
+			return result, fmt.Errorf("import json: unknown record %q", r.Type)
+		}
+	}
+	return result, nil
+}
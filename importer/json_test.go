@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wrong.wang/x/go-isso/database"
+	"wrong.wang/x/go-isso/exporter"
+	"wrong.wang/x/go-isso/isso"
+)
+
+func TestFromJSON_roundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-isso-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	src := newStorage(t, dir)
+	defer src.Close()
+	src.ExactVotes = true
+	fillStorage(t, src)
+
+	var first bytes.Buffer
+	if err := exporter.JSON(ctx, src, &first); err != nil {
+		t.Fatalf("exporter.JSON() error = %v", err)
+	}
+	if strings.Contains(first.String(), "hask-key") {
+		t.Errorf("secret preference should not be exported")
+	}
+	if !strings.Contains(first.String(), `"type":"vote"`) || !strings.Contains(first.String(), `"type":"reaction"`) {
+		t.Errorf("votes and reactions should be exported")
+	}
+	path := filepath.Join(dir, "export.ndjson")
+	if err := ioutil.WriteFile(path, first.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := database.New(filepath.Join(dir, "restored.db"), 1*time.Second)
+	if err != nil {
+		t.Fatalf("init database failed: %v", err)
+	}
+	defer dst.Close()
+	dst.ExactVotes = true
+	result, err := FromJSON(ctx, dst, path, Options{})
+	if err != nil {
+		t.Fatalf("FromJSON() error = %v", err)
+	}
+	if want := (Result{Threads: 2, Comments: 4, Votes: 1, Reactions: 1, Preferences: 2}); result != want {
+		t.Errorf("FromJSON() = %v, want %v", result, want)
+	}
+	checkVoters(t, dst)
+
+	var second bytes.Buffer
+	if err := exporter.JSON(ctx, dst, &second); err != nil {
+		t.Fatalf("exporter.JSON() error = %v", err)
+	}
+	// skip headers, they have different timestamps
+	got := second.String()[strings.Index(second.String(), "\n"):]
+	want := first.String()[strings.Index(first.String(), "\n"):]
+	if got != want {
+		t.Errorf("round trip changed the document:\ngot  %s\nwant %s", got, want)
+	}
+
+	result, err = FromJSON(ctx, dst, path, Options{})
+	if err != nil {
+		t.Fatalf("FromJSON() again error = %v", err)
+	}
+	if want := (Result{Skipped: 10}); result != want {
+		t.Errorf("FromJSON() again = %v, want %v", result, want)
+	}
+}
+
+func TestFromJSON_newerVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-isso-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	storage := newStorage(t, dir)
+	defer storage.Close()
+
+	path := filepath.Join(dir, "export.ndjson")
+	if err := ioutil.WriteFile(path, []byte(`{"type":"header","header":{"version":99}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FromJSON(context.Background(), storage, path, Options{}); err == nil {
+		t.Errorf("FromJSON() should refuse newer document")
+	}
+}
+
+// checkVoters check the voters of fillStorage are recognized by storage.
+func checkVoters(t *testing.T, storage isso.Storage) {
+	t.Helper()
+	ctx := context.Background()
+	hello, err := storage.GetThreadByURI(ctx, "/hello/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	votes, err := storage.ListVotes(ctx, hello.ID)
+	if err != nil || len(votes) != 1 {
+		t.Fatalf("ListVotes() = %+v, %v", votes, err)
+	}
+	if _, _, err := storage.VoteComment(ctx, votes[0].CommentID, "10.0.0.9", true); !errors.Is(err, isso.ErrAlreadyVoted) {
+		t.Errorf("VoteComment() by imported voter error = %v, want %v", err, isso.ErrAlreadyVoted)
+	}
+	reactions, err := storage.ListReactions(ctx, hello.ID)
+	if err != nil || len(reactions) != 1 {
+		t.Fatalf("ListReactions() = %+v, %v", reactions, err)
+	}
+	if _, err := storage.React(ctx, reactions[0].CommentID, "10.0.0.8", "heart"); !errors.Is(err, isso.ErrAlreadyVoted) {
+		t.Errorf("React() by imported voter error = %v, want %v", err, isso.ErrAlreadyVoted)
+	}
+}
+
+// fillStorage create two threads with a reply, a vote, a reaction, a deleted comment and preferences.
+func fillStorage(t *testing.T, storage isso.Storage) {
+	ctx := context.Background()
+	email := "alice@example.com"
+	hello, err := storage.NewThread(ctx, "/hello/", "Hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	about, err := storage.NewThread(ctx, "/about/", "About")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := storage.NewComment(ctx, isso.Comment{Text: "first", Author: "alice", Email: &email,
+		Mode: isso.ModeAccepted, Notification: 1}, hello.ID, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := storage.NewComment(ctx, isso.Comment{Text: "reply", Author: "bob", Parent: &first.ID,
+		Mode: isso.ModeModeration}, hello.ID, "10.0.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.NewComment(ctx, isso.Comment{Text: "again", Author: "carol", Parent: &reply.ID,
+		Mode: isso.ModeAccepted}, hello.ID, "10.0.0.3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := storage.VoteComment(ctx, first.ID, "10.0.0.9", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.React(ctx, reply.ID, "10.0.0.8", "heart"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.DeleteComment(ctx, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.NewComment(ctx, isso.Comment{Text: "about", Author: "dave",
+		Mode: isso.ModeAccepted}, about.ID, "10.0.0.4"); err != nil {
+		t.Fatal(err)
+	}
+	// a raw key, as old versions saved it
+	if err := storage.SetPreference("hask-key", "\x00\xffsecret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.SetPreference("theme", "dark"); err != nil {
+		t.Fatal(err)
+	}
+}
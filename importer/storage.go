@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// ErrNotEmpty is returned by FromStorage when the destination has threads already.
+var ErrNotEmpty = errors.New("import storage: destination is not empty")
+
+// FromStorage copy threads, comments, votes, reactions and preferences of src into the
+// empty storage dst, ids, parents, votes counts and the secret preferences are kept as
+// they are, so dst can replace src. The voters of votes and reactions are only recognized
+// with isso.VoterKeyPreference of src. Keys saved as raw bytes by old versions are copied
+// hex encoded, see isso.EncodeKeyPreference.
+// Everything is copied in one transaction, and the comments, votes and reactions of every
+// thread are counted in dst again before it is committed, so a failed copy leaves dst empty
+// and can be run again.
+func FromStorage(ctx context.Context, dst isso.Storage, src isso.Storage, opts Options) (Result, error) {
+	var result Result
+	threads, err := src.ListThreads(ctx)
+	if err != nil {
+		return result, fmt.Errorf("import storage: %w", err)
+	}
+	existing, err := dst.ListThreads(ctx)
+	if err != nil {
+		return result, fmt.Errorf("import storage: %w", err)
+	}
+	if len(existing) > 0 {
+		return result, fmt.Errorf("%w: %d threads", ErrNotEmpty, len(existing))
+	}
+
+	preferences, err := src.ListPreferences()
+	if err != nil {
+		return result, fmt.Errorf("import storage: %w", err)
+	}
+	if key, ok := preferences[isso.VoterKeyPreference]; ok {
+		if old, err := dst.GetPreference(isso.VoterKeyPreference); err == nil && old != key {
+			return result, fmt.Errorf("%w: %s differs, voters of src would not be recognized",
+				ErrNotEmpty, isso.VoterKeyPreference)
+		}
+	}
+	copied := map[string]string{}
+	for key, value := range preferences {
+		if _, err := dst.GetPreference(key); err == nil {
+			result.Skipped++
+			continue
+		}
+		copied[key] = isso.EncodeKeyPreference(key, value)
+		result.Preferences++
+	}
+
+	contents := make([]threadContent, 0, len(threads))
+	for _, thread := range threads {
+		c := threadContent{thread: thread}
+		if c.comments, err = src.ListComments(ctx, thread.ID); err != nil {
+			return result, fmt.Errorf("import storage: list comments of %s: %w", thread.URI, err)
+		}
+		if c.votes, err = src.ListVotes(ctx, thread.ID); err != nil {
+			return result, fmt.Errorf("import storage: list votes of %s: %w", thread.URI, err)
+		}
+		if c.reactions, err = src.ListReactions(ctx, thread.ID); err != nil {
+			return result, fmt.Errorf("import storage: list reactions of %s: %w", thread.URI, err)
+		}
+		contents = append(contents, c)
+		result.Threads++
+		result.Comments += len(c.comments)
+		result.Votes += len(c.votes)
+		result.Reactions += len(c.reactions)
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	err = dst.WithTx(ctx, func(tx isso.Storage) error {
+		for key, value := range copied {
+			if err := tx.SetPreference(key, value); err != nil {
+				return fmt.Errorf("preference %s: %w", key, err)
+			}
+		}
+		for _, c := range contents {
+			if err := c.restore(ctx, tx); err != nil {
+				return fmt.Errorf("thread %s: %w", c.thread.URI, err)
+			}
+		}
+		return verifyCounts(ctx, tx, contents)
+	})
+	if err != nil {
+		return result, fmt.Errorf("import storage: %w", err)
+	}
+	return result, nil
+}
+
+// threadContent is a thread of src with its comments, votes and reactions.
+type threadContent struct {
+	thread    isso.Thread
+	comments  []isso.Comment
+	votes     []isso.Vote
+	reactions []isso.Reaction
+}
+
+// restore save c into dst.
+func (c threadContent) restore(ctx context.Context, dst isso.Storage) error {
+	if _, err := dst.RestoreThread(ctx, c.thread); err != nil {
+		return err
+	}
+	// parents have smaller ids, they are restored before their replies
+	for _, comment := range c.comments {
+		if _, err := dst.RestoreComment(ctx, comment, c.thread.ID); err != nil {
+			return fmt.Errorf("comment %d: %w", comment.ID, err)
+		}
+	}
+	for _, v := range c.votes {
+		if err := dst.RestoreVote(ctx, v); err != nil {
+			return fmt.Errorf("vote of comment %d: %w", v.CommentID, err)
+		}
+	}
+	for _, r := range c.reactions {
+		if err := dst.RestoreReaction(ctx, r); err != nil {
+			return fmt.Errorf("reaction of comment %d: %w", r.CommentID, err)
+		}
+	}
+	return nil
+}
+
+// threadCounts is what a thread has.
+type threadCounts struct {
+	comments, votes, reactions int
+}
+
+// verifyCounts check every thread of dst has the same uri and count of comments, votes
+// and reactions as in src.
+func verifyCounts(ctx context.Context, dst isso.Storage, contents []threadContent) error {
+	for _, c := range contents {
+		thread := c.thread
+		got, err := dst.GetThreadByID(ctx, thread.ID)
+		if err != nil {
+			return fmt.Errorf("verify thread %d: %w", thread.ID, err)
+		}
+		if got.URI != thread.URI {
+			return fmt.Errorf("verify thread %d: uri is %s, want %s", thread.ID, got.URI, thread.URI)
+		}
+		comments, err := dst.ListComments(ctx, thread.ID)
+		if err != nil {
+			return fmt.Errorf("verify thread %s: %w", thread.URI, err)
+		}
+		votes, err := dst.ListVotes(ctx, thread.ID)
+		if err != nil {
+			return fmt.Errorf("verify thread %s: %w", thread.URI, err)
+		}
+		reactions, err := dst.ListReactions(ctx, thread.ID)
+		if err != nil {
+			return fmt.Errorf("verify thread %s: %w", thread.URI, err)
+		}
+		want := threadCounts{len(c.comments), len(c.votes), len(c.reactions)}
+		if got := (threadCounts{len(comments), len(votes), len(reactions)}); got != want {
+			return fmt.Errorf("verify thread %s: %d comments, %d votes and %d reactions, want %d, %d and %d",
+				thread.URI, got.comments, got.votes, got.reactions, want.comments, want.votes, want.reactions)
+		}
+	}
+	return nil
+}
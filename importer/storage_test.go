@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/memory"
+)
+
+func TestFromStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-isso-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+
+	src := newStorage(t, dir)
+	defer src.Close()
+	src.ExactVotes = true
+	fillStorage(t, src)
+
+	// a destination with another voter key would not recognize the voters
+	other := memory.New()
+	if err := other.SetPreference(isso.VoterKeyPreference, "another"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FromStorage(ctx, other, src, Options{}); !errors.Is(err, ErrNotEmpty) {
+		t.Errorf("FromStorage() into storage with another voter key error = %v, want %v", err, ErrNotEmpty)
+	}
+
+	dst := memory.New()
+	dst.ExactVotes = true
+	result, err := FromStorage(ctx, dst, src, Options{DryRun: true})
+	if want := (Result{Threads: 2, Comments: 4, Votes: 1, Reactions: 1, Preferences: 3}); err != nil || result != want {
+		t.Errorf("FromStorage() dry run = %v, %v, want %v", result, err, want)
+	}
+	if threads, _ := dst.ListThreads(ctx); len(threads) != 0 {
+		t.Errorf("dry run should not write threads, got %v", threads)
+	}
+
+	result, err = FromStorage(ctx, dst, src, Options{})
+	if want := (Result{Threads: 2, Comments: 4, Votes: 1, Reactions: 1, Preferences: 3}); err != nil || result != want {
+		t.Fatalf("FromStorage() = %v, %v, want %v", result, err, want)
+	}
+	threads, err := src.ListThreads(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, thread := range threads {
+		want, _ := src.ListComments(ctx, thread.ID)
+		got, err := dst.ListComments(ctx, thread.ID)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ListComments(%s) = %+v, %v, want %+v", thread.URI, got, err, want)
+		}
+		wantVotes, _ := src.ListVotes(ctx, thread.ID)
+		if got, err := dst.ListVotes(ctx, thread.ID); err != nil || !reflect.DeepEqual(got, wantVotes) {
+			t.Errorf("ListVotes(%s) = %+v, %v, want %+v", thread.URI, got, err, wantVotes)
+		}
+		wantReactions, _ := src.ListReactions(ctx, thread.ID)
+		if got, err := dst.ListReactions(ctx, thread.ID); err != nil || !reflect.DeepEqual(got, wantReactions) {
+			t.Errorf("ListReactions(%s) = %+v, %v, want %+v", thread.URI, got, err, wantReactions)
+		}
+	}
+	checkVoters(t, dst)
+	// the raw key is copied hex encoded, so PostgreSQL and MySQL can save it
+	if key, err := dst.GetPreference("hask-key"); err != nil || key != "00ff736563726574" {
+		t.Errorf("GetPreference(hask-key) = %s, %v, secret preferences should be copied", key, err)
+	}
+
+	if _, err := FromStorage(ctx, dst, src, Options{}); !errors.Is(err, ErrNotEmpty) {
+		t.Errorf("FromStorage() into non-empty storage error = %v, want %v", err, ErrNotEmpty)
+	}
+	// the copy works backwards too
+	backDir, err := ioutil.TempDir("", "go-isso-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(backDir)
+	back := newStorage(t, backDir)
+	defer back.Close()
+	if _, err := FromStorage(ctx, back, dst, Options{}); err != nil {
+		t.Errorf("FromStorage() from memory error = %v", err)
+	}
+}
+
+// failingStorage fail to restore comment failID.
+type failingStorage struct {
+	isso.Storage
+	failID int64
+}
+
+var errRestore = errors.New("restore failed")
+
+func (s failingStorage) WithTx(ctx context.Context, fn func(isso.Storage) error) error {
+	return s.Storage.WithTx(ctx, func(tx isso.Storage) error { return fn(failingStorage{tx, s.failID}) })
+}
+
+func (s failingStorage) RestoreComment(ctx context.Context, c isso.Comment, tid int64) (isso.Comment, error) {
+	if c.ID == s.failID {
+		return isso.Comment{}, errRestore
+	}
+	return s.Storage.RestoreComment(ctx, c, tid)
+}
+
+func TestFromStorage_rollback(t *testing.T) {
+	ctx := context.Background()
+	src := memory.New()
+	fillStorage(t, src)
+	threads, err := src.ListThreads(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := src.ListComments(ctx, threads[len(threads)-1].ID)
+	if err != nil || len(last) == 0 {
+		t.Fatalf("ListComments() = %v, %v", last, err)
+	}
+
+	// the last thread fails, nothing of the earlier ones is left behind
+	dst := memory.New()
+	if _, err := FromStorage(ctx, failingStorage{dst, last[len(last)-1].ID}, src, Options{}); !errors.Is(err, errRestore) {
+		t.Fatalf("FromStorage() error = %v, want %v", err, errRestore)
+	}
+	if threads, err := dst.ListThreads(ctx); err != nil || len(threads) != 0 {
+		t.Errorf("ListThreads() after failed copy = %v, %v, want none", threads, err)
+	}
+	if preferences, err := dst.ListPreferences(); err != nil || len(preferences) != 0 {
+		t.Errorf("ListPreferences() after failed copy = %v, %v, want none", preferences, err)
+	}
+
+	// so it can be run again
+	if _, err := FromStorage(ctx, dst, src, Options{}); err != nil {
+		t.Errorf("FromStorage() again error = %v", err)
+	}
+}
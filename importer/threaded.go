@@ -0,0 +1,235 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/logger"
+	"wrong.wang/x/go-isso/tool/bloomfilter"
+)
+
+// foreignComment is a comment from other systems, which refers to its thread
+// and parent with their foreign ids.
+type foreignComment struct {
+	isso.Comment
+	id       string
+	threadID string
+	parentID string
+}
+
+type foreignThread struct {
+	uri   string
+	title string
+	// id is 0 until the first comment of the thread is imported.
+	id       int64
+	loaded   bool
+	existing map[string]int64
+}
+
+// threadedImporter import comments which may arrive before their thread or
+// parent, and make sure a comment is never imported twice. Deleted comments are
+// only imported as the top comment of replies, like a soft delete keeps them.
+type threadedImporter struct {
+	ctx     context.Context
+	storage isso.Storage
+	opts    Options
+	result  Result
+
+	threads map[string]*foreignThread
+	// roots map foreign comment id to the foreign id of its top comment
+	roots    map[string]string
+	comments map[string]int64
+	pending  []foreignComment
+	// deleted are the deleted top comments without imported replies yet
+	deleted map[string]foreignComment
+}
+
+func newThreadedImporter(ctx context.Context, storage isso.Storage, opts Options) *threadedImporter {
+	return &threadedImporter{
+		ctx:      ctx,
+		storage:  storage,
+		opts:     opts,
+		threads:  map[string]*foreignThread{},
+		roots:    map[string]string{},
+		comments: map[string]int64{},
+		deleted:  map[string]foreignComment{},
+	}
+}
+
+func (ti *threadedImporter) addThread(id, link, title string) {
+	ti.threads[id] = &foreignThread{uri: ti.opts.Rewrite.Rewrite(link), title: title}
+}
+
+// add import c, or keep it until its thread and parent are known.
+func (ti *threadedImporter) add(c foreignComment) error {
+	done, err := ti.tryAdd(c)
+	if err != nil || done {
+		return err
+	}
+	ti.pending = append(ti.pending, c)
+	return nil
+}
+
+// flush import pending comments. Comments whose parent is not in the export
+// become top comments, comments without a known thread are skipped.
+func (ti *threadedImporter) flush() error {
+	for progress := true; progress; {
+		progress = false
+		var rest []foreignComment
+		for _, c := range ti.pending {
+			done, err := ti.tryAdd(c)
+			if err != nil {
+				return err
+			}
+			if done {
+				progress = true
+				continue
+			}
+			rest = append(rest, c)
+		}
+		ti.pending = rest
+		if !progress && len(ti.pending) > 0 {
+			// break the chain at the first comment with a missing parent
+			for i, c := range ti.pending {
+				if _, ok := ti.threads[c.threadID]; ok && c.parentID != "" {
+					logger.Info("parent %s of comment %s not found, import it as top comment", c.parentID, c.id)
+					ti.pending[i].parentID = ""
+					progress = true
+					break
+				}
+			}
+		}
+	}
+	for _, c := range ti.pending {
+		logger.Error("thread %s of comment %s not found, skip it", c.threadID, c.id)
+		ti.result.Skipped++
+	}
+	ti.pending = nil
+	ti.result.Skipped += len(ti.deleted)
+	ti.deleted = map[string]foreignComment{}
+	return nil
+}
+
+func (ti *threadedImporter) tryAdd(c foreignComment) (bool, error) {
+	t, ok := ti.threads[c.threadID]
+	if !ok {
+		return false, nil
+	}
+	root := c.id
+	if c.parentID != "" {
+		if root, ok = ti.roots[c.parentID]; !ok {
+			return false, nil
+		}
+	}
+	if err := ti.loadThread(t); err != nil {
+		return false, err
+	}
+
+	if id, ok := t.existing[foreignKey(c.Comment)]; ok {
+		ti.roots[c.id] = root
+		ti.comments[c.id] = id
+		ti.result.Skipped++
+		return true, nil
+	}
+
+	if c.Mode == isso.ModeDeleted {
+		ti.roots[c.id] = root
+		if root == c.id {
+			ti.deleted[c.id] = c
+		} else {
+			// replies are saved as replies of their top comment, a deleted one has none
+			ti.result.Skipped++
+		}
+		return true, nil
+	}
+
+	if root != c.id {
+		parent, ok := ti.comments[root]
+		if !ok {
+			// the deleted top comment is restored with its first reply
+			var err error
+			if parent, err = ti.restore(t, ti.deleted[root]); err != nil {
+				return false, err
+			}
+			delete(ti.deleted, root)
+			ti.comments[root] = parent
+		}
+		c.Parent = &parent
+	}
+	id, err := ti.restore(t, c)
+	if err != nil {
+		return false, err
+	}
+	ti.roots[c.id] = root
+	ti.comments[c.id] = id
+	return true, nil
+}
+
+// restore save c into thread t, which is created with its first comment, and return the id of c.
+func (ti *threadedImporter) restore(t *foreignThread, c foreignComment) (int64, error) {
+	bf := bloomfilter.New()
+	bf.Add([]byte(c.RemoteAddr))
+	c.Voters = bf.Buffer()
+
+	if !ti.opts.DryRun {
+		if t.id == 0 {
+			thread, err := ti.storage.RestoreThread(ti.ctx, isso.Thread{URI: t.uri, Title: t.title})
+			if err != nil {
+				return 0, err
+			}
+			t.id = thread.ID
+			ti.result.Threads++
+		}
+		saved, err := ti.storage.RestoreComment(ti.ctx, c.Comment, t.id)
+		if err != nil {
+			return 0, err
+		}
+		c.ID = saved.ID
+	} else if t.id == 0 {
+		// a fake id, so the thread is counted only once
+		t.id = -1
+		ti.result.Threads++
+	}
+	t.existing[foreignKey(c.Comment)] = c.ID
+	ti.result.Comments++
+	return c.ID, nil
+}
+
+// loadThread find the existed thread of t and its comments.
+func (ti *threadedImporter) loadThread(t *foreignThread) error {
+	if t.loaded {
+		return nil
+	}
+	t.loaded = true
+	t.existing = map[string]int64{}
+
+	thread, err := ti.storage.GetThreadByURI(ti.ctx, t.uri)
+	if errors.Is(err, isso.ErrStorageNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	t.id = thread.ID
+	commentsByParent, err := ti.storage.FetchCommentsByURI(ti.ctx, t.uri, -1,
+		isso.ModeAccepted|isso.ModeModeration|isso.ModeDeleted, "id", true)
+	if err != nil {
+		return err
+	}
+	for _, comments := range commentsByParent {
+		for _, c := range comments {
+			t.existing[foreignKey(c)] = c.ID
+		}
+	}
+	return nil
+}
+
+// foreignKey identify an imported comment, exported timestamps only have
+// second precision, so comments of an author posted in the same second are
+// told apart by their text.
+func foreignKey(c isso.Comment) string {
+	return fmt.Sprintf("%d|%s|%s|%x", int64(c.Created), c.RemoteAddr, c.Author, sha256.Sum256([]byte(c.Text)))
+}
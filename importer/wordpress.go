@@ -0,0 +1,134 @@
+package importer
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+const wxrTimeLayout = "2006-01-02 15:04:05"
+
+type wxrItem struct {
+	Title    string       `xml:"title"`
+	Link     string       `xml:"link"`
+	PostID   string       `xml:"post_id"`
+	Comments []wxrComment `xml:"comment"`
+}
+
+type wxrComment struct {
+	ID          string `xml:"comment_id"`
+	Author      string `xml:"comment_author"`
+	AuthorEmail string `xml:"comment_author_email"`
+	AuthorURL   string `xml:"comment_author_url"`
+	AuthorIP    string `xml:"comment_author_IP"`
+	Date        string `xml:"comment_date"`
+	DateGMT     string `xml:"comment_date_gmt"`
+	Content     string `xml:"comment_content"`
+	Approved    string `xml:"comment_approved"`
+	Type        string `xml:"comment_type"`
+	Parent      string `xml:"comment_parent"`
+}
+
+// FromWordPress import comments of a WordPress eXtended RSS (WXR) export.
+// Pingbacks and trackbacks are skipped. Like FromDisqus, running it again will
+// not duplicate threads or comments, and replies are nested one level deep.
+func FromWordPress(ctx context.Context, storage isso.Storage, path string, opts Options) (Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("import wordpress: %w", err)
+	}
+	defer f.Close()
+
+	wi := newThreadedImporter(ctx, storage, opts)
+	decoder := xml.NewDecoder(f)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return wi.result, fmt.Errorf("import wordpress: parse xml failed: %w", err)
+		}
+		se, ok := token.(xml.StartElement)
+		if !ok || se.Name.Local != "item" {
+			continue
+		}
+		var item wxrItem
+		if err := decoder.DecodeElement(&item, &se); err != nil {
+			return wi.result, fmt.Errorf("import wordpress: parse item failed: %w", err)
+		}
+		if len(item.Comments) == 0 {
+			continue
+		}
+		wi.addThread(item.PostID, item.Link, item.Title)
+		for _, wc := range item.Comments {
+			if wc.Type == "pingback" || wc.Type == "trackback" {
+				wi.result.Skipped++
+				continue
+			}
+			c, err := wordpressComment(item.PostID, wc)
+			if err != nil {
+				return wi.result, fmt.Errorf("import wordpress: comment %s: %w", wc.ID, err)
+			}
+			if err := wi.add(c); err != nil {
+				return wi.result, fmt.Errorf("import wordpress: comment %s: %w", wc.ID, err)
+			}
+		}
+	}
+	if err := wi.flush(); err != nil {
+		return wi.result, fmt.Errorf("import wordpress: %w", err)
+	}
+	return wi.result, nil
+}
+
+func wordpressComment(postID string, wc wxrComment) (foreignComment, error) {
+	created, err := time.ParseInLocation(wxrTimeLayout, wc.DateGMT, time.UTC)
+	if err != nil {
+		// comment_date_gmt may be "0000-00-00 00:00:00"
+		created, err = time.ParseInLocation(wxrTimeLayout, wc.Date, time.UTC)
+		if err != nil {
+			return foreignComment{}, err
+		}
+	}
+	c := foreignComment{
+		id:       wc.ID,
+		threadID: postID,
+	}
+	if wc.Parent != "" && wc.Parent != "0" {
+		c.parentID = wc.Parent
+	}
+	c.Created = float64(created.Unix())
+	c.Mode = wordpressMode(wc.Approved)
+	c.RemoteAddr = wc.AuthorIP
+	c.Text = htmlToMarkdown(wc.Content)
+	c.Author = wc.Author
+	if wc.AuthorEmail != "" {
+		email := wc.AuthorEmail
+		c.Email = &email
+	}
+	if wc.AuthorURL != "" {
+		website := wc.AuthorURL
+		c.Website = &website
+	}
+	if c.Mode == isso.ModeDeleted {
+		c.Text, c.Author, c.Email, c.Website = "", "", nil, nil
+	}
+	return c, nil
+}
+
+// wordpressMode map comment_approved, which is one of `1`, `0`, `spam` and `trash`.
+func wordpressMode(approved string) int {
+	switch approved {
+	case "1":
+		return isso.ModeAccepted
+	case "trash":
+		return isso.ModeDeleted
+	default:
+		return isso.ModeModeration
+	}
+}
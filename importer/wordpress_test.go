@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func TestFromWordPress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-isso-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	storage := newStorage(t, dir)
+	defer storage.Close()
+	ctx := context.Background()
+	opts := Options{Rewrite: URIRewriter{OldPrefix: "/blog/", NewPrefix: "/posts/"}}
+
+	result, err := FromWordPress(ctx, storage, "testdata/wordpress.xml", opts)
+	if err != nil {
+		t.Fatalf("FromWordPress() error = %v", err)
+	}
+	if want := (Result{Threads: 1, Comments: 4, Skipped: 2}); result != want {
+		t.Errorf("FromWordPress() = %v, want %v", result, want)
+	}
+
+	thread, err := storage.GetThreadByURI(ctx, "/posts/2020/01/hello-world/")
+	if err != nil || thread.Title != "Hello world!" {
+		t.Fatalf("GetThreadByURI() = %v, %v", thread, err)
+	}
+	commentsByParent, err := storage.FetchCommentsByURI(ctx, thread.URI, -1,
+		isso.ModeAccepted|isso.ModeModeration|isso.ModeDeleted, "id", true)
+	if err != nil {
+		t.Fatalf("FetchCommentsByURI() error = %v", err)
+	}
+	top := commentsByParent[0]
+	if len(top) != 2 {
+		t.Fatalf("want 2 top comments, got %+v", top)
+	}
+	alice := top[0]
+	if alice.Author != "Alice" || alice.Mode != isso.ModeAccepted || alice.Created != 1577876400 ||
+		alice.Website == nil || *alice.Website != "https://alice.example.com" {
+		t.Errorf("comment = %+v", alice)
+	}
+	// the trashed comment without replies is not imported, the other is kept for its reply
+	trashed := top[1]
+	if trashed.Mode != isso.ModeDeleted || trashed.Text != "" || trashed.Email != nil {
+		t.Errorf("trashed comment = %+v", trashed)
+	}
+	if replies := commentsByParent[trashed.ID]; len(replies) != 1 || replies[0].Author != "Dave" {
+		t.Errorf("replies of trashed comment = %+v", replies)
+	}
+	replies := commentsByParent[alice.ID]
+	if len(replies) != 1 || replies[0].Mode != isso.ModeModeration || replies[0].Text != "Thanks *Alice*" ||
+		replies[0].Created != 1577959200 {
+		t.Errorf("replies = %+v", replies)
+	}
+	checkConsistency(t, storage)
+
+	result, err = FromWordPress(ctx, storage, "testdata/wordpress.xml", opts)
+	if err != nil {
+		t.Fatalf("FromWordPress() error = %v", err)
+	}
+	if want := (Result{Skipped: 6}); result != want {
+		t.Errorf("FromWordPress() again = %v, want %v", result, want)
+	}
+}
+
+func TestURIRewriter_Rewrite(t *testing.T) {
+	tests := []struct {
+		name string
+		r    URIRewriter
+		link string
+		want string
+	}{
+		{"strip domain", URIRewriter{}, "https://example.com/a/b/", "/a/b/"},
+		{"keep query", URIRewriter{}, "https://example.com/?p=12", "/?p=12"},
+		{"keep domain", URIRewriter{KeepDomain: true}, "https://example.com/a/", "https://example.com/a/"},
+		{"prefix", URIRewriter{OldPrefix: "/blog", NewPrefix: ""}, "https://example.com/blog/a/", "/a/"},
+		{"relative", URIRewriter{}, "/a/", "/a/"},
+		{"front page", URIRewriter{}, "https://example.com", "/"},
+		{"front page with query", URIRewriter{}, "https://example.com?p=1", "/?p=1"},
+		{"prefix of front page", URIRewriter{OldPrefix: "/blog", NewPrefix: ""}, "https://example.com/blog", "/"},
+		{"front page keep domain", URIRewriter{KeepDomain: true}, "https://example.com", "https://example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.Rewrite(tt.link); got != tt.want {
+				t.Errorf("URIRewriter.Rewrite() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
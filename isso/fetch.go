@@ -0,0 +1,126 @@
+package isso
+
+import (
+	"context"
+)
+
+// FetchParam is the parameters of fetching comments of a thread.
+type FetchParam struct {
+	// Parent nil means top comments and their replies, otherwise only replies of Parent.
+	Parent *int64
+	// Limit and NestedLimit limit the amount of top comments and replies, 0 means no limit.
+	Limit       int64
+	NestedLimit int64
+	// After only fetch comments created after it.
+	After float64
+	// Plain keep text unrendered.
+	Plain bool
+}
+
+// FetchResult is the comment tree of a thread.
+type FetchResult struct {
+	TotalReplies  int64   `json:"total_replies"`
+	Replies       []Reply `json:"replies"`
+	ID            *int64  `json:"id"`
+	HiddenReplies int64   `json:"hidden_replies"`
+}
+
+// Fetch return the public comments of uri as the API `GET /?uri=` does.
+func (isso *ISSO) Fetch(ctx context.Context, uri string, p FetchParam) (FetchResult, error) {
+	var reactions map[int64]map[string]int
+	if len(isso.config.Reactions) > 0 {
+		var err error
+		if reactions, err = isso.storage.CountReactions(ctx, uri); err != nil {
+			return FetchResult{}, err
+		}
+	}
+	var render func(Comment) (string, error)
+	makeReplies := func(cs []Comment, after float64, limit int64) []Reply {
+		var replies []Reply
+		var count int64
+		if limit <= 0 {
+			limit = int64(len(cs) + 1)
+		}
+		for _, c := range cs {
+			if c.Created > after && count < limit {
+				count++
+				r, _ := c.convert(isso.tools.hash, render)
+				r.Reactions = reactions[c.ID]
+				replies = append(replies, r)
+			}
+		}
+		return replies
+	}
+
+	var parent int64
+	if p.Parent == nil {
+		parent = -1
+	} else {
+		parent = *p.Parent
+	}
+
+	replyCount, err := isso.storage.CountReply(ctx, uri, ModePublic, p.After)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	// param `after` may cause the loss of old comment's parent
+	if _, ok := replyCount[parent]; !ok {
+		replyCount[parent] = 0
+	}
+
+	commentsByParent, err := isso.storage.FetchCommentsByURI(ctx, uri, parent, ModePublic, "id", true)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	if !p.Plain {
+		saved, err := isso.storage.GetRendered(ctx, uri)
+		if err != nil {
+			return FetchResult{}, err
+		}
+		// all public comments of uri are loaded already, mentions are resolved against them
+		var thread map[int64][]Comment
+		if parent == -1 {
+			thread = commentsByParent
+		}
+		render = isso.render(ctx, uri, thread, saved, true)
+	}
+	rJSON := FetchResult{
+		ID: p.Parent,
+	}
+
+	// null parent, only fetch top-comment
+	if parent == -1 {
+		// parent == -1 means need all comment's, here TotalReplies means top-leval comments
+		rJSON.TotalReplies = replyCount[0]
+
+		rJSON.Replies = makeReplies(commentsByParent[0], p.After, p.Limit)
+		rJSON.HiddenReplies = rJSON.TotalReplies - int64(len(rJSON.Replies))
+		var zero int64
+		emptyarray := make([]Reply, 0)
+		for i := range rJSON.Replies {
+			count, ok := replyCount[rJSON.Replies[i].ID]
+			if !ok {
+				rJSON.Replies[i].TotalReplies = &zero
+				rJSON.Replies[i].Replies = &emptyarray
+				rJSON.Replies[i].HiddenReplies = &zero
+			} else {
+				replies := makeReplies(commentsByParent[rJSON.Replies[i].ID], p.After, p.NestedLimit)
+				rJSON.Replies[i].TotalReplies = &count
+				rJSON.Replies[i].Replies = &replies
+				cc := *rJSON.Replies[i].TotalReplies - int64(len(*rJSON.Replies[i].Replies))
+				rJSON.Replies[i].HiddenReplies = &cc
+			}
+		}
+
+	} else if parent > 0 {
+		rJSON.TotalReplies = replyCount[parent]
+		rJSON.Replies = makeReplies(commentsByParent[parent], p.After, p.Limit)
+		rJSON.HiddenReplies = rJSON.TotalReplies - int64(len(rJSON.Replies))
+	} else {
+		// parent = 0 not exist
+		rJSON.TotalReplies = 0
+		rJSON.Replies = []Reply{}
+		rJSON.HiddenReplies = 0
+	}
+	return rJSON, nil
+}
@@ -7,19 +7,15 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 	"wrong.wang/x/go-isso/extract"
 	"wrong.wang/x/go-isso/response/json"
-	"wrong.wang/x/go-isso/tool/bloomfilter"
 	"wrong.wang/x/go-isso/tool/validator"
 )
 
-const maxlikeanddislikes = 142
-
 // CreateComment create a new comment
 func (isso *ISSO) CreateComment() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -42,8 +38,8 @@ func (isso *ISSO) CreateComment() http.HandlerFunc {
 			json.BadRequest(requestID, w, err, fmt.Sprintf("comment validate failed: %s", err.Error()))
 			return
 		}
-		if comment.Website != nil && (strings.HasPrefix(*comment.Website, "https://") || strings.HasPrefix(*comment.Website, "http://")) {
-			*comment.Website = "http://" + *comment.Website
+		if comment.Website != nil && !isso.tools.links.AllowWebsite(*comment.Website) {
+			comment.Website = nil
 		}
 
 		ok, reason := isso.newCommentGuard(r.Context(), comment.Comment, comment.URI)
@@ -98,7 +94,7 @@ func (isso *ISSO) CreateComment() http.HandlerFunc {
 		}
 		isso.tools.event.Publish("comments.new:after-save", thread, c)
 
-		reply, _ := c.convert(false, isso.tools.hash, isso.tools.markdown)
+		reply, _ := c.convert(isso.tools.hash, isso.render(r.Context(), thread.URI, nil, nil, true))
 
 		isso.tools.event.Publish("comments.new:finish", thread, c)
 
@@ -127,6 +123,15 @@ func (isso *ISSO) newCommentGuard(ctx context.Context, c Comment, uri string) (b
 	return isso.storage.NewCommentGuard(ctx, c, uri, g.RateLimit, g.DirectReply, g.ReplyToSelf, isso.config.MaxAge)
 }
 
+// approvedAuthor report whether the author of c has an approved comment in 6 months.
+// Only the link policy tells approved authors apart, without it everyone is approved.
+func (isso *ISSO) approvedAuthor(ctx context.Context, c Comment) bool {
+	if !isso.tools.links.BareLinksAsText {
+		return true
+	}
+	return c.Email != nil && isso.storage.IsApprovedAuthor(ctx, *c.Email)
+}
+
 // FetchComments fetch all related comments
 func (isso *ISSO) FetchComments() http.HandlerFunc {
 	type urlParm struct {
@@ -139,22 +144,6 @@ func (isso *ISSO) FetchComments() http.HandlerFunc {
 	decoder := schema.NewDecoder()
 	decoder.IgnoreUnknownKeys(true)
 
-	makeReplies := func(cs []Comment, after float64, limit int64, plain bool) []reply {
-		var replies []reply
-		var count int64
-		if limit <= 0 {
-			limit = int64(len(cs) + 1)
-		}
-		for _, c := range cs {
-			if c.Created > after && count < limit {
-				count++
-				r, _ := c.convert(plain, isso.tools.hash, isso.tools.markdown)
-				replies = append(replies, r)
-			}
-		}
-		return replies
-	}
-
 	return func(w http.ResponseWriter, r *http.Request) {
 		requestID := RequestIDFromContext(r.Context())
 		var urlparm urlParm
@@ -163,76 +152,19 @@ func (isso *ISSO) FetchComments() http.HandlerFunc {
 			json.BadRequest(requestID, w, err, descRequestInvalidParm)
 			return
 		}
-		var parent int64
-		if urlparm.Parent == nil {
-			parent = -1
-		} else {
-			parent = *urlparm.Parent
-		}
-		var plain bool
-		if urlparm.Plain != 0 {
-			plain = true
-		}
 
-		replyCount, err := isso.storage.CountReply(r.Context(), mux.Vars(r)["uri"], ModePublic, urlparm.After)
+		result, err := isso.Fetch(r.Context(), mux.Vars(r)["uri"], FetchParam{
+			Parent:      urlparm.Parent,
+			Limit:       urlparm.Limit,
+			NestedLimit: urlparm.NestedLimit,
+			After:       urlparm.After,
+			Plain:       urlparm.Plain != 0,
+		})
 		if err != nil {
 			json.ServerError(requestID, w, err, descStorageUnhandledError)
 			return
 		}
-		// param `after` may cause the loss of old comment's parent
-		if _, ok := replyCount[parent]; !ok {
-			replyCount[parent] = 0
-		}
-
-		commentsByParent, err := isso.storage.FetchCommentsByURI(r.Context(), mux.Vars(r)["uri"], parent, ModePublic, "id", true)
-		if err != nil {
-			json.ServerError(requestID, w, err, descStorageUnhandledError)
-			return
-		}
-		rJSON := struct {
-			TotalReplies  int64   `json:"total_replies"`
-			Replies       []reply `json:"replies"`
-			ID            *int64  `json:"id"`
-			HiddenReplies int64   `json:"hidden_replies"`
-		}{
-			ID: urlparm.Parent,
-		}
-
-		// null parent, only fetch top-comment
-		if parent == -1 {
-			// parent == -1 means need all comment's, here TotalReplies means top-leval comments
-			rJSON.TotalReplies = replyCount[0]
-
-			rJSON.Replies = makeReplies(commentsByParent[0], urlparm.After, urlparm.Limit, plain)
-			rJSON.HiddenReplies = rJSON.TotalReplies - int64(len(rJSON.Replies))
-			var zero int64
-			emptyarray := make([]reply, 0)
-			for i := range rJSON.Replies {
-				count, ok := replyCount[rJSON.Replies[i].ID]
-				if !ok {
-					rJSON.Replies[i].TotalReplies = &zero
-					rJSON.Replies[i].Replies = &emptyarray
-					rJSON.Replies[i].HiddenReplies = &zero
-				} else {
-					replies := makeReplies(commentsByParent[rJSON.Replies[i].ID], urlparm.After, urlparm.NestedLimit, plain)
-					rJSON.Replies[i].TotalReplies = &count
-					rJSON.Replies[i].Replies = &replies
-					cc := *rJSON.Replies[i].TotalReplies - int64(len(*rJSON.Replies[i].Replies))
-					rJSON.Replies[i].HiddenReplies = &cc
-				}
-			}
-
-		} else if parent > 0 {
-			rJSON.TotalReplies = replyCount[parent]
-			rJSON.Replies = makeReplies(commentsByParent[parent], urlparm.After, urlparm.Limit, plain)
-			rJSON.HiddenReplies = rJSON.TotalReplies - int64(len(rJSON.Replies))
-		} else {
-			// parent = 0 not exist
-			rJSON.TotalReplies = 0
-			rJSON.Replies = []reply{}
-			rJSON.HiddenReplies = 0
-		}
-		json.OK(w, rJSON)
+		json.OK(w, result)
 	}
 }
 
@@ -289,7 +221,22 @@ func (isso *ISSO) ViewComment() http.HandlerFunc {
 			return
 		}
 
-		r, _ := comment.convert(plain, isso.tools.hash, isso.tools.markdown)
+		var render func(Comment) (string, error)
+		if !plain {
+			thread, err := isso.storage.GetThreadByCommentID(req.Context(), id)
+			if err != nil {
+				json.ServerError(requestID, w, err, descStorageUnhandledError)
+				return
+			}
+			render = isso.render(req.Context(), thread.URI, nil, nil, false)
+		}
+		r, _ := comment.convert(isso.tools.hash, render)
+		if len(isso.config.Reactions) > 0 {
+			if r.Reactions, err = isso.storage.GetReactions(req.Context(), id); err != nil {
+				json.ServerError(requestID, w, err, descStorageUnhandledError)
+				return
+			}
+		}
 		json.OK(w, r)
 	}
 }
@@ -329,7 +276,7 @@ func (isso *ISSO) EditComment() http.HandlerFunc {
 		if ei.Email != nil {
 			comment.Email = ei.Email
 		}
-		if ei.Website != nil {
+		if ei.Website != nil && isso.tools.links.AllowWebsite(*ei.Website) {
 			comment.Website = ei.Website
 		}
 		comment.Modified = new(float64)
@@ -343,13 +290,18 @@ func (isso *ISSO) EditComment() http.HandlerFunc {
 
 		isso.tools.event.Publish("comments.edit", c)
 
-		reply, _ := c.convert(false, isso.tools.hash, isso.tools.markdown)
+		thread, err := isso.storage.GetThreadByCommentID(r.Context(), c.ID)
+		if err != nil {
+			json.ServerError(requestID, w, err, descStorageUnhandledError)
+			return
+		}
+		reply, _ := c.convert(isso.tools.hash, isso.render(r.Context(), thread.URI, nil, nil, true))
 		isso.setcookie(c, w, false)
 		json.OK(w, reply)
 	}
 }
 
-// VoteComment used to like or dislike comment
+// VoteComment used to like or dislike comment, or retract it with DELETE
 func (isso *ISSO) VoteComment() http.HandlerFunc {
 	type vresponse struct {
 		Likes    int    `json:"likes"`
@@ -373,46 +325,31 @@ func (isso *ISSO) VoteComment() http.HandlerFunc {
 			return
 		}
 
-		c, err := isso.storage.GetComment(r.Context(), cid)
-		if err != nil {
-			if errors.Is(err, ErrStorageNotFound) {
-				json.NotFound(requestID, w, err, descStorageNotFound)
-				return
-			}
-			json.ServerError(requestID, w, err, descStorageUnhandledError)
-			return
+		remoteAddr := findClientIP(r)
+		var vr vresponse
+		if r.Method == http.MethodDelete {
+			vr.Likes, vr.Dislikes, err = isso.storage.UnvoteComment(r.Context(), cid, remoteAddr, upvote)
+		} else {
+			vr.Likes, vr.Dislikes, err = isso.storage.VoteComment(r.Context(), cid, remoteAddr, upvote)
 		}
-
-		vr := vresponse{Likes: c.Likes, Dislikes: c.Dislikes}
-
-		if c.Likes+c.Dislikes > maxlikeanddislikes {
+		switch {
+		case errors.Is(err, ErrTooManyVotes):
 			vr.Msg = fmt.Sprintf(`denied due to a "likes + dislikes" total too high (%d > %d)`,
-				c.Likes+c.Dislikes, maxlikeanddislikes)
-			json.OK(w, vr)
-			return
-		}
-
-		remoteAddr := findClientIP(r)
-		bf := bloomfilter.RecoverFrom(c.Voters, c.Likes+c.Dislikes)
-		if bf.Contains([]byte(remoteAddr)) {
+				vr.Likes+vr.Dislikes, MaxVotes)
+		case errors.Is(err, ErrAlreadyVoted):
 			vr.Msg = fmt.Sprintf(`denied because a vote has already been registered for this remote address: %s`, remoteAddr)
-			json.OK(w, vr)
+		case errors.Is(err, ErrNotVoted):
+			vr.Msg = fmt.Sprintf(`no %s has been registered for this remote address: %s`, mux.Vars(r)["vote"], remoteAddr)
+		case errors.Is(err, ErrNotSupported):
+			json.BadRequest(requestID, w, err, descRequestInvalidParm)
 			return
-		}
-		bf.Add([]byte(remoteAddr))
-		c.Voters = bf.Buffer()
-
-		err = isso.storage.VoteComment(r.Context(), c, upvote)
-		if err != nil {
+		case errors.Is(err, ErrStorageNotFound):
+			json.NotFound(requestID, w, err, descStorageNotFound)
+			return
+		case err != nil:
 			json.ServerError(requestID, w, err, descStorageUnhandledError)
 			return
 		}
-
-		if upvote {
-			vr.Likes++
-		} else {
-			vr.Dislikes++
-		}
 		json.OK(w, vr)
 		return
 	}
@@ -435,7 +372,7 @@ func (isso *ISSO) DeleteComment() http.HandlerFunc {
 
 		isso.tools.event.Publish("comments.delete", comment.ID)
 
-		reply, _ := comment.convert(false, isso.tools.hash, isso.tools.markdown)
+		reply, _ := comment.convert(isso.tools.hash, nil)
 		isso.setcookie(comment, w, true)
 		json.OK(w, reply)
 	}
@@ -534,3 +471,67 @@ func (isso *ISSO) setcookie(c Comment, w http.ResponseWriter, delete bool) {
 		}
 	}
 }
+
+// ReactComment used to add a reaction to comment, or retract it with DELETE
+func (isso *ISSO) ReactComment() http.HandlerFunc {
+	type rresponse struct {
+		Reactions map[string]int `json:"reactions"`
+		Msg       string         `json:"message,omitempty"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := RequestIDFromContext(r.Context())
+		cid, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+		if err != nil {
+			json.BadRequest(requestID, w, err, descRequestInvalidParm)
+			return
+		}
+		reaction := mux.Vars(r)["reaction"]
+		if !isso.allowReaction(reaction) {
+			json.BadRequest(requestID, w, nil, fmt.Sprintf("reaction %s is not allowed", reaction))
+			return
+		}
+
+		remoteAddr := findClientIP(r)
+		var rr rresponse
+		if r.Method == http.MethodDelete {
+			rr.Reactions, err = isso.storage.Unreact(r.Context(), cid, remoteAddr, reaction)
+		} else {
+			if g := isso.config.Server.Guard; g.Enable {
+				if ok, reason := isso.storage.ReactionGuard(r.Context(), remoteAddr, g.RateLimit); !ok {
+					json.Forbidden(requestID, w, nil, reason)
+					return
+				}
+			}
+			rr.Reactions, err = isso.storage.React(r.Context(), cid, remoteAddr, reaction)
+		}
+		switch {
+		case errors.Is(err, ErrAlreadyVoted):
+			rr.Msg = fmt.Sprintf(`denied because %s has already been registered for this remote address: %s`, reaction, remoteAddr)
+		case errors.Is(err, ErrNotVoted):
+			rr.Msg = fmt.Sprintf(`no %s has been registered for this remote address: %s`, reaction, remoteAddr)
+		case errors.Is(err, ErrStorageNotFound):
+			json.NotFound(requestID, w, err, descStorageNotFound)
+			return
+		case err != nil:
+			json.ServerError(requestID, w, err, descStorageUnhandledError)
+			return
+		}
+		if rr.Msg != "" {
+			// counts are not returned with a denied reaction
+			if rr.Reactions, err = isso.storage.GetReactions(r.Context(), cid); err != nil {
+				json.ServerError(requestID, w, err, descStorageUnhandledError)
+				return
+			}
+		}
+		json.OK(w, rr)
+	}
+}
+
+func (isso *ISSO) allowReaction(reaction string) bool {
+	for _, allowed := range isso.config.Reactions {
+		if reaction == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,8 @@ package isso
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 
 	"github.com/gorilla/securecookie"
 	"wrong.wang/x/go-isso/config"
@@ -35,42 +37,121 @@ type ISSO struct {
 	storage Storage
 	config  config.Config
 	tools   tools
+	// readOnly never save rendered html to storage
+	readOnly bool
 }
 
 type tools struct {
 	securecookie *securecookie.SecureCookie
 	hash         *hash.Worker
-	markdown     *markdown.Worker
+	markdown     markdown.Markup
+	renderer     string
+	links        markdown.LinkPolicy
+	mentions     bool
 	event        *event.Bus
 }
 
+// HashKeyPreference and BlockKeyPreference are the preferences of the keys cookies are
+// signed and encrypted with, they are created by New and saved hex encoded.
+const (
+	HashKeyPreference  = "hask-key"
+	BlockKeyPreference = "block-key"
+)
+
 // New a ISSO instance
 func New(cfg config.Config, storage Storage) *ISSO {
-	var HashKey, BlockKey string
-	var err error
-	if HashKey, err = storage.GetPreference("hask-key"); err != nil {
-		HashKey = string(securecookie.GenerateRandomKey(64))
-		err := storage.SetPreference("hask-key", HashKey)
-		if err != nil {
-			logger.Fatal("set hash-key failed %w", err)
-		}
+	hashKey, err := loadKey(storage, HashKeyPreference, 64)
+	if err != nil {
+		logger.Fatal("load %s failed: %v", HashKeyPreference, err)
 	}
-	if BlockKey, err = storage.GetPreference("block-key"); err != nil {
-		BlockKey = string(securecookie.GenerateRandomKey(32))
-		err := storage.SetPreference("block-key", BlockKey)
-		if err != nil {
-			logger.Fatal("set block-key failed %w", err)
+	blockKey, err := loadKey(storage, BlockKeyPreference, 32)
+	if err != nil {
+		logger.Fatal("load %s failed: %v", BlockKeyPreference, err)
+	}
+	isso := newISSO(cfg, storage)
+	isso.tools.securecookie = securecookie.New(hashKey, blockKey)
+	return isso
+}
+
+// loadKey return the key of preference name, a random key of size bytes is created if
+// storage has none.
+func loadKey(storage PreferenceStorage, name string, size int) ([]byte, error) {
+	value, err := storage.GetPreference(name)
+	if errors.Is(err, ErrStorageNotFound) {
+		value = hex.EncodeToString(securecookie.GenerateRandomKey(size))
+		if err = storage.SetPreference(name, value); err != nil {
+			// another process may have created it at the same time
+			value, err = storage.GetPreference(name)
 		}
 	}
-	BlockKey = string(securecookie.GenerateRandomKey(32))
-	HashKey = string(securecookie.GenerateRandomKey(64))
+	if err != nil {
+		return nil, err
+	}
+	return decodeKey(value), nil
+}
+
+// decodeKey return the key saved as value, old versions saved the raw bytes.
+func decodeKey(value string) []byte {
+	if key, err := hex.DecodeString(value); err == nil {
+		return key
+	}
+	return []byte(value)
+}
+
+// EncodeKeyPreference return value of preference name hex encoded if it is a key saved as
+// raw bytes by old versions, which the text columns of PostgreSQL and MySQL refuse. Other
+// values are returned as they are.
+func EncodeKeyPreference(name, value string) string {
+	if name != HashKeyPreference && name != BlockKeyPreference {
+		return value
+	}
+	return hex.EncodeToString(decodeKey(value))
+}
+
+// NewRenderer return an ISSO fetching and rendering comments of storage, e.g. for a static
+// export. Unlike New it never writes to storage, neither the keys of the installation nor
+// the rendered html of comments.
+func NewRenderer(cfg config.Config, storage Storage) *ISSO {
+	isso := newISSO(cfg, storage)
+	isso.readOnly = true
+	return isso
+}
+
+func newISSO(cfg config.Config, storage Storage) *ISSO {
+	markup := cfg.Server.Guard.Markup
+	links := markdown.LinkPolicy{
+		TargetBlank:     markup.LinkTargetBlank,
+		AllowedDomains:  markup.LinkAllowedDomains,
+		BlockedDomains:  markup.LinkBlockedDomains,
+		BareLinksAsText: markup.UnapprovedBareLinksText,
+	}
+	opts := markdown.Options{
+		Extensions:        markup.Extensions,
+		AllowedElements:   markup.AllowedElements,
+		AllowedAttributes: markup.AllowedAttributes,
+
+		Highlight:          markup.Highlight,
+		HighlightLanguages: markup.HighlightLanguages,
+		HighlightMaxBytes:  markup.HighlightMaxSize,
+
+		Links: links,
+	}
+	md, err := markdown.NewMarkup(markup.Language, opts)
+	if err != nil {
+		logger.Fatal("markup config error: %v", err)
+	}
+	BlockKey := string(securecookie.GenerateRandomKey(32))
+	HashKey := string(securecookie.GenerateRandomKey(64))
 	return &ISSO{
 		config: cfg,
 		tools: tools{
 			securecookie: securecookie.New([]byte(HashKey), []byte(BlockKey)),
 			// TODO: use conf to special hash
 			hash:     hash.New("pbkdf2:1000:6:sha1", "Eech7co8Ohloopo9Ol6baimi"),
-			markdown: markdown.New(),
+			markdown: md,
+			renderer: markdown.Renderer(markup.Language, opts),
+			links:    links,
+			mentions: opts.HasExtension("mention"),
 			event:    event.New(),
 		},
 		storage: storage,
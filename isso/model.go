@@ -25,25 +25,52 @@ type Comment struct {
 	Voters       [256]byte `json:"-"`
 }
 
+// Rendered is the html rendered from the text of a comment.
+type Rendered struct {
+	// Renderer identify the markup which rendered HTML, and Checksum the text it is rendered from.
+	// HTML is stale if either differs.
+	Renderer string
+	Checksum string
+	HTML     string
+}
+
+// Vote is the exact vote of a voter on a comment, Voter is hashed by the storage.
+type Vote struct {
+	CommentID int64
+	Voter     string
+	// Value is 1 for a like, -1 for a dislike and 0 for a retracted vote.
+	Value   int
+	Created float64
+}
+
+// Reaction is a reaction of a voter on a comment, Voter is hashed by the storage.
+type Reaction struct {
+	CommentID int64
+	Voter     string
+	Reaction  string
+	Created   float64
+}
+
 type submittedComment struct {
 	Comment
 	URI   string `json:"-" validate:"required,uri"`
 	Title string `json:"title" validate:"omitempty"`
 }
 
-type reply struct {
+// Reply is a comment as it is responded to readers, email removed and text rendered.
+type Reply struct {
 	Comment
 	Hash          string   `json:"hash"`
 	HiddenReplies *int64   `json:"hidden_replies,omitempty"`
 	TotalReplies  *int64   `json:"total_replies,omitempty"`
-	Replies       *[]reply `json:"replies,omitempty"`
+	Replies       *[]Reply `json:"replies,omitempty"`
+	// Reactions is the count of each reaction, like {"👍": 2}
+	Reactions map[string]int `json:"reactions,omitempty"`
 }
 
-// Convert remove email from comment, and markdownify if not `plain`
-// if markdown convert failed, c.Text will be origin text. but return error is not nil
-func (c Comment) convert(plain bool, hash interface{ Hash(string) string }, markdown interface {
-	Convert(source string) (string, error)
-}) (reply, error) {
+// Convert remove email from comment, and render its text to html with render, unless render is nil.
+// if render failed, c.Text will be origin text. but return error is not nil
+func (c Comment) convert(hash interface{ Hash(string) string }, render func(Comment) (string, error)) (Reply, error) {
 
 	// hash comment
 	var hashresult string
@@ -53,17 +80,17 @@ func (c Comment) convert(plain bool, hash interface{ Hash(string) string }, mark
 		hashresult = hash.Hash(c.RemoteAddr)
 	}
 
-	// remove email
-	c.Email = nil
-
 	// markdowify
-	if plain {
-		return reply{c, hashresult, nil, nil, nil}, nil
-	}
-	text, err := markdown.Convert(c.Text)
-	if err != nil {
-		return reply{c, hashresult, nil, nil, nil}, err
+	if render != nil {
+		text, err := render(c)
+		if err != nil {
+			c.Email = nil
+			return Reply{Comment: c, Hash: hashresult}, err
+		}
+		c.Text = text
 	}
-	c.Text = text
-	return reply{c, hashresult, nil, nil, nil}, nil
+
+	// remove email
+	c.Email = nil
+	return Reply{Comment: c, Hash: hashresult}, nil
 }
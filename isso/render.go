@@ -0,0 +1,198 @@
+package isso
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"wrong.wang/x/go-isso/logger"
+	"wrong.wang/x/go-isso/tool/markdown"
+)
+
+// checksum identify the text html is rendered from.
+func checksum(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// rendererOf identify the markup rendering comments of approved or unapproved authors.
+func (isso *ISSO) rendererOf(approved bool) string {
+	if approved {
+		return isso.tools.renderer
+	}
+	return isso.tools.renderer + "-unapproved"
+}
+
+// approvedAuthors return approvedAuthor which checks an email once.
+func (isso *ISSO) approvedAuthors(ctx context.Context) func(Comment) bool {
+	approved := map[string]bool{}
+	return func(c Comment) bool {
+		if c.Email == nil {
+			return isso.approvedAuthor(ctx, c)
+		}
+		ok, cached := approved[*c.Email]
+		if !cached {
+			ok = isso.approvedAuthor(ctx, c)
+			approved[*c.Email] = ok
+		}
+		return ok
+	}
+}
+
+// mentionsOf return the authors @mentioned in the text of c with their latest comment before c,
+// thread is the public comments of the thread of c ordered by id.
+func mentionsOf(c Comment, thread []Comment) map[string]int64 {
+	text := strings.ToLower(c.Text)
+	var mentions map[string]int64
+	for _, t := range thread {
+		if t.ID >= c.ID {
+			break
+		}
+		if t.Author == "" || t.Mode == ModeDeleted || !strings.Contains(text, "@"+strings.ToLower(t.Author)) {
+			continue
+		}
+		if mentions == nil {
+			mentions = map[string]int64{}
+		}
+		mentions[t.Author] = t.ID
+	}
+	return mentions
+}
+
+// textChecksum is the checksum of the text of c and the comments it mentions.
+func textChecksum(c Comment, mentions map[string]int64) string {
+	if len(mentions) == 0 {
+		return checksum(c.Text)
+	}
+	authors := make([]string, 0, len(mentions))
+	for author := range mentions {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+	var b strings.Builder
+	b.WriteString(c.Text)
+	for _, author := range authors {
+		fmt.Fprintf(&b, "\x00%s=%d", author, mentions[author])
+	}
+	return checksum(b.String())
+}
+
+// upToDate report whether r is rendered from the text of c by the current markup.
+func (isso *ISSO) upToDate(r Rendered, c Comment, approved bool, mentions map[string]int64) bool {
+	return r.Renderer == isso.rendererOf(approved) && r.Checksum == textChecksum(c, mentions)
+}
+
+// renderText render the text of c by the current markup.
+func (isso *ISSO) renderText(c Comment, approved bool, mentions map[string]int64) (Rendered, error) {
+	html, err := isso.tools.markdown.ConvertWith(c.Text, markdown.ConvertOptions{Unapproved: !approved, Mentions: mentions})
+	if err != nil {
+		return Rendered{}, err
+	}
+	return Rendered{Renderer: isso.rendererOf(approved), Checksum: textChecksum(c, mentions), HTML: html}, nil
+}
+
+// threadMentions return mentionsOf of comments of uri. byParent is all public comments of uri
+// if the caller has loaded them, otherwise they are loaded once when a text may mention
+// someone. Without uri or the mention extension nothing is mentioned.
+func (isso *ISSO) threadMentions(ctx context.Context, uri string, byParent map[int64][]Comment) func(Comment) map[string]int64 {
+	var thread []Comment
+	loaded := byParent != nil
+	for _, cs := range byParent {
+		thread = append(thread, cs...)
+	}
+	sort.Slice(thread, func(i, j int) bool { return thread[i].ID < thread[j].ID })
+	return func(c Comment) map[string]int64 {
+		if !isso.tools.mentions || uri == "" || !strings.Contains(c.Text, "@") {
+			return nil
+		}
+		if !loaded {
+			loaded = true
+			byParent, err := isso.storage.FetchCommentsByURI(ctx, uri, -1, ModePublic, "id", true)
+			if err != nil {
+				logger.Error("fetch comments of %s for mentions failed: %v", uri, err)
+			}
+			for _, cs := range byParent {
+				thread = append(thread, cs...)
+			}
+			sort.Slice(thread, func(i, j int) bool { return thread[i].ID < thread[j].ID })
+		}
+		return mentionsOf(c, thread)
+	}
+}
+
+// render return a function rendering the text of comments of uri to html, @mentions are
+// resolved against the comments of uri, or not at all if uri is empty. byParent is all public
+// comments of uri if they are loaded already, see threadMentions. The html in saved is reused
+// if it is up to date, otherwise the text is rendered and, if save and isso is not read-only,
+// saved to storage. Failing to save is logged only, the text is rendered again next time.
+func (isso *ISSO) render(ctx context.Context, uri string, byParent map[int64][]Comment, saved map[int64]Rendered, save bool) func(Comment) (string, error) {
+	approvedAuthor := isso.approvedAuthors(ctx)
+	mentionsOf := isso.threadMentions(ctx, uri, byParent)
+	save = save && !isso.readOnly
+	return func(c Comment) (string, error) {
+		approved := approvedAuthor(c)
+		mentions := mentionsOf(c)
+		if r, ok := saved[c.ID]; ok && isso.upToDate(r, c, approved, mentions) {
+			return r.HTML, nil
+		}
+		r, err := isso.renderText(c, approved, mentions)
+		if err != nil {
+			return "", err
+		}
+		if save {
+			if err := isso.storage.SetRendered(ctx, c.ID, r); err != nil {
+				logger.Error("save rendered html of comment %d failed: %v", c.ID, err)
+			}
+		}
+		return r.HTML, nil
+	}
+}
+
+// Rerender render the text of comments again and save the html, and return how many comments
+// are rendered. Unless all, comments with up-to-date html are skipped.
+func (isso *ISSO) Rerender(ctx context.Context, all bool) (int, error) {
+	threads, err := isso.storage.ListThreads(ctx)
+	if err != nil {
+		return 0, err
+	}
+	approvedAuthor := isso.approvedAuthors(ctx)
+	var n int
+	for _, t := range threads {
+		saved, err := isso.storage.GetRendered(ctx, t.URI)
+		if err != nil {
+			return n, err
+		}
+		comments, err := isso.storage.ListComments(ctx, t.ID)
+		if err != nil {
+			return n, err
+		}
+		var public []Comment
+		for _, c := range comments {
+			if c.Mode&ModePublic != 0 {
+				public = append(public, c)
+			}
+		}
+		for _, c := range comments {
+			approved := approvedAuthor(c)
+			var mentions map[string]int64
+			if isso.tools.mentions {
+				mentions = mentionsOf(c, public)
+			}
+			if r, ok := saved[c.ID]; ok && !all && isso.upToDate(r, c, approved, mentions) {
+				continue
+			}
+			r, err := isso.renderText(c, approved, mentions)
+			if err != nil {
+				return n, err
+			}
+			if err := isso.storage.SetRendered(ctx, c.ID, r); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
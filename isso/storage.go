@@ -13,8 +13,20 @@ var (
 	ErrNotExpectAmount = errors.New("storage: affected amount is not equal as expect")
 	// ErrInvalidParam is returned by databStoragease method when input param check failed.
 	ErrInvalidParam = errors.New("storage: handler input is not valid")
+	// ErrAlreadyVoted is returned by VoteComment when voter has voted the comment.
+	ErrAlreadyVoted = errors.New("storage: voter has already voted")
+	// ErrTooManyVotes is returned by VoteComment when the comment has MaxVotes votes.
+	ErrTooManyVotes = errors.New("storage: too many votes")
+	// ErrNotVoted is returned by UnvoteComment when voter has no such vote.
+	ErrNotVoted = errors.New("storage: voter has not voted")
+	// ErrNotSupported is returned when the storage is not configured for the operation.
+	ErrNotSupported = errors.New("storage: operation is not supported")
 )
 
+// MaxVotes is the most likes and dislikes a comment can have without exact voters,
+// the 256 bytes bloom filter of voters is not reliable for more.
+const MaxVotes = 142
+
 // mode for comment's mode. comment mode CAN NOT be set to modePublic.
 const (
 	//modeAccepted means The comment was accepted by the server and is published.
@@ -39,33 +51,118 @@ type Storage interface {
 	ThreadStorage
 	CommentStorage
 	PreferenceStorage
+	RestoreStorage
+	ReactionStorage
+	RenderStorage
+	// NewCommentGuard deny c if its RemoteAddr has more than ratelimit comments in 60s,
+	// more than directreply top comments in thread uri, or, unless replytoself,
+	// c replies its own comment created in maxage seconds.
 	NewCommentGuard(ctx context.Context, c Comment, uri string,
 		ratelimit int, directreply int, replytoself bool, maxage int) (bool, string)
+	// WithTx run fn in a transaction, it is committed when fn return nil and rolled back otherwise.
+	// fn must use the Storage it is given, not the outer one. Nested calls join the outer transaction.
+	WithTx(ctx context.Context, fn func(Storage) error) error
 }
 
 // ThreadStorage handles all operations related to Thread and the database.
 type ThreadStorage interface {
 	GetThreadByURI(ctx context.Context, uri string) (Thread, error)
 	GetThreadByID(ctx context.Context, id int64) (Thread, error)
+	// GetThreadByCommentID return the thread comment cid belongs to.
+	GetThreadByCommentID(ctx context.Context, cid int64) (Thread, error)
+	// NewThread fails if uri exists, ErrInvalidParam is returned for empty uri or title.
+	// A thread is removed with its last comment.
 	NewThread(ctx context.Context, uri string, title string) (Thread, error)
+	// ListThreads return all threads ordered by id.
+	ListThreads(ctx context.Context) ([]Thread, error)
 }
 
 // CommentStorage handles all operations related to Comment and the database.
 type CommentStorage interface {
+	// IsApprovedAuthor report whether email has an accepted comment in 6 months.
 	IsApprovedAuthor(ctx context.Context, email string) bool
+	// NewComment save c with a new id and created time, a reply to a reply is saved as
+	// a reply to its top comment. The parent must be in threadID, or ErrInvalidParam is returned.
 	NewComment(ctx context.Context, c Comment, threadID int64, remoteAddr string) (Comment, error)
 	GetComment(ctx context.Context, id int64) (Comment, error)
-	// CountReply return parent-count map, 0 mean null `parent`
+	// CountReply return parent-count map of comments of uri created after `after`,
+	// 0 mean null `parent`. Like every mode argument, mode is a bit mask,
+	// a comment counts if its mode is in mask, e.g. ModePublic.
 	CountReply(ctx context.Context, uri string, mode int, after float64) (map[int64]int64, error)
+	// FetchCommentsByURI return comments of uri grouped by parent, 0 mean null `parent`.
+	// parent -1 means all comments, 0 top comments and others replies of parent.
+	// orderBy is one of id, created, modified, likes and dislikes, id is used otherwise.
 	FetchCommentsByURI(ctx context.Context, uri string, parent int64, mode int, orderBy string, asc bool) (map[int64][]Comment, error)
+	// CountComment return the count of accepted comments of every uri.
 	CountComment(ctx context.Context, uris []string) (map[string]int64, error)
+	// EditComment change text, author, website, email and modified of c.ID.
+	// ErrInvalidParam is returned for nil c.Modified, ErrNotExpectAmount if c.ID does not exist.
 	EditComment(ctx context.Context, c Comment) (Comment, error)
+	// DeleteComment mark comment cid deleted and return it if it has replies, otherwise remove it
+	// and return an empty Comment. Then deleted comments without replies are removed.
 	DeleteComment(ctx context.Context, cid int64) (Comment, error)
-	VoteComment(ctx context.Context, c Comment, up bool) error
+	// VoteComment add a like or dislike of voter to comment id atomically, and return
+	// likes and dislikes after it. ErrAlreadyVoted and ErrTooManyVotes are returned with
+	// the unchanged likes and dislikes.
+	VoteComment(ctx context.Context, id int64, voter string, up bool) (likes int, dislikes int, err error)
+	// UnvoteComment retract a like or dislike of voter from comment id, and return likes and
+	// dislikes after it. ErrNotVoted is returned if there is no such vote.
+	UnvoteComment(ctx context.Context, id int64, voter string, up bool) (likes int, dislikes int, err error)
+	// ListComments return all comments of thread in any mode, ordered by id.
+	ListComments(ctx context.Context, threadID int64) ([]Comment, error)
+}
+
+// ReactionStorage handles all operations related to reactions like 👍 on comments.
+// A voter can give a comment each reaction once.
+type ReactionStorage interface {
+	// React add reaction of voter to comment id and return reaction counts of the comment.
+	// ErrAlreadyVoted is returned if voter has given the reaction or is the author of the comment.
+	React(ctx context.Context, id int64, voter string, reaction string) (map[string]int, error)
+	// Unreact retract reaction of voter from comment id and return reaction counts of the comment.
+	// ErrNotVoted is returned if voter has not given the reaction.
+	Unreact(ctx context.Context, id int64, voter string, reaction string) (map[string]int, error)
+	// GetReactions return reaction counts of comment id.
+	GetReactions(ctx context.Context, id int64) (map[string]int, error)
+	// CountReactions return reaction counts of comments of uri by comment id.
+	CountReactions(ctx context.Context, uri string) (map[int64]map[string]int, error)
+	// ReactionGuard limit reactions of voter in 60s to ratelimit
+	ReactionGuard(ctx context.Context, voter string, ratelimit int) (bool, string)
+}
+
+// RenderStorage saves the html rendered from the text of comments, so it is not rendered
+// on every fetch. It is removed with the comment.
+type RenderStorage interface {
+	// SetRendered save r as the html of comment id, replacing the saved one.
+	// ErrStorageNotFound is returned if comment id does not exist.
+	SetRendered(ctx context.Context, id int64, r Rendered) error
+	// GetRendered return the saved html of comments of uri by comment id.
+	GetRendered(ctx context.Context, uri string) (map[int64]Rendered, error)
 }
 
 // PreferenceStorage handles all operations related to Preference and the database.
 type PreferenceStorage interface {
 	GetPreference(key string) (string, error)
+	// SetPreference fails if key exists.
 	SetPreference(key string, value string) error
+	ListPreferences() (map[string]string, error)
+}
+
+// RestoreStorage writes threads and comments as they are, keeping ids, timestamps,
+// modes and votes, and lists and writes the voters of votes and reactions.
+// It is used by importers and exporters, never by HTTP handlers.
+type RestoreStorage interface {
+	// RestoreThread save t with t.ID, zero t.ID let storage choose a new one.
+	RestoreThread(ctx context.Context, t Thread) (Thread, error)
+	// RestoreComment save c with c.ID, zero c.ID let storage choose a new one.
+	// c.Parent is kept as is, it is not flattened like NewComment does.
+	RestoreComment(ctx context.Context, c Comment, threadID int64) (Comment, error)
+	// ListVotes return exact votes of comments of thread, ordered by comment id and voter.
+	ListVotes(ctx context.Context, threadID int64) ([]Vote, error)
+	// RestoreVote save v with its hashed voter, likes and dislikes of the comment are
+	// restored with it and not changed.
+	RestoreVote(ctx context.Context, v Vote) error
+	// ListReactions return reactions of comments of thread, ordered by comment id, voter and reaction.
+	ListReactions(ctx context.Context, threadID int64) ([]Reaction, error)
+	// RestoreReaction save r with its hashed voter.
+	RestoreReaction(ctx context.Context, r Reaction) error
 }
@@ -0,0 +1,295 @@
+package storagetest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func testNewComment(t *testing.T, s isso.Storage) {
+	thread, comments := newThread(t, s, "/post/", "a", "ra")
+	other, _ := newThread(t, s, "/other/", "b")
+
+	a := comments["a"]
+	if a.ID == 0 || a.Parent != nil || a.Created == 0 || a.Modified != nil || a.RemoteAddr != "10.0.0.1" {
+		t.Errorf("NewComment() = %+v", a)
+	}
+	got, err := s.GetComment(ctx, a.ID)
+	if err != nil || !reflect.DeepEqual(got, a) {
+		t.Errorf("GetComment() = %+v, %v, want %+v", got, err, a)
+	}
+	if _, err := s.GetComment(ctx, a.ID+100); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("GetComment() error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+	if got, err := s.GetThreadByCommentID(ctx, a.ID); err != nil || !reflect.DeepEqual(got, thread) {
+		t.Errorf("GetThreadByCommentID() = %+v, %v, want %+v", got, err, thread)
+	}
+	if _, err := s.GetThreadByCommentID(ctx, a.ID+100); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("GetThreadByCommentID() error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+
+	// reply to a reply is a reply to the top comment
+	ra := comments["ra"]
+	rra, err := s.NewComment(ctx, isso.Comment{Text: "rra", Parent: &ra.ID, Mode: isso.ModeAccepted}, thread.ID, "10.0.0.2")
+	if err != nil {
+		t.Fatalf("NewComment() error = %v", err)
+	}
+	if rra.Parent == nil || *rra.Parent != a.ID {
+		t.Errorf("NewComment() parent = %v, want %d", rra.Parent, a.ID)
+	}
+
+	if _, err := s.NewComment(ctx, isso.Comment{Text: "x", Parent: &a.ID}, other.ID, "10.0.0.2"); !errors.Is(err, isso.ErrInvalidParam) {
+		t.Errorf("NewComment() replying comment of another thread error = %v, want %v", err, isso.ErrInvalidParam)
+	}
+	missing := a.ID + 100
+	if _, err := s.NewComment(ctx, isso.Comment{Text: "x", Parent: &missing}, thread.ID, "10.0.0.2"); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("NewComment() replying missing comment error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+
+	email := "alice@example.com"
+	if s.IsApprovedAuthor(ctx, email) {
+		t.Errorf("IsApprovedAuthor() without comments = true")
+	}
+	if _, err := s.NewComment(ctx, isso.Comment{Text: "moderated", Email: &email, Mode: isso.ModeModeration}, thread.ID, "10.0.0.3"); err != nil {
+		t.Fatalf("NewComment() error = %v", err)
+	}
+	if s.IsApprovedAuthor(ctx, email) {
+		t.Errorf("IsApprovedAuthor() with comment in moderation = true")
+	}
+	if _, err := s.NewComment(ctx, isso.Comment{Text: "accepted", Email: &email, Mode: isso.ModeAccepted}, thread.ID, "10.0.0.3"); err != nil {
+		t.Fatalf("NewComment() error = %v", err)
+	}
+	if !s.IsApprovedAuthor(ctx, email) {
+		t.Errorf("IsApprovedAuthor() with accepted comment = false")
+	}
+}
+
+func testFetchComments(t *testing.T, s isso.Storage) {
+	thread, comments := newThread(t, s, "/post/", "a", "ra1", "ra2", "b", "rb")
+	newThread(t, s, "/other/", "c")
+	if _, err := s.NewComment(ctx, isso.Comment{Text: "pending", Mode: isso.ModeModeration}, thread.ID, "10.0.0.2"); err != nil {
+		t.Fatalf("NewComment() error = %v", err)
+	}
+	a, b := comments["a"].ID, comments["b"].ID
+
+	counts, err := s.CountReply(ctx, "/post/", isso.ModePublic, 0)
+	if err != nil {
+		t.Fatalf("CountReply() error = %v", err)
+	}
+	if want := map[int64]int64{0: 2, a: 2, b: 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("CountReply() = %v, want %v", counts, want)
+	}
+	counts, err = s.CountReply(ctx, "/post/", isso.ModeAccepted|isso.ModeModeration|isso.ModeDeleted, 0)
+	if err != nil {
+		t.Fatalf("CountReply() error = %v", err)
+	}
+	if want := map[int64]int64{0: 3, a: 2, b: 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("CountReply() of all modes = %v, want %v", counts, want)
+	}
+	counts, err = s.CountReply(ctx, "/post/", isso.ModePublic, comments["rb"].Created)
+	if err != nil || len(counts) != 0 {
+		t.Errorf("CountReply() after the last comment = %v, %v", counts, err)
+	}
+
+	ids := func(cs []isso.Comment) []int64 {
+		var ids []int64
+		for _, c := range cs {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	byParent, err := s.FetchCommentsByURI(ctx, "/post/", -1, isso.ModePublic, "id", true)
+	if err != nil {
+		t.Fatalf("FetchCommentsByURI() error = %v", err)
+	}
+	if len(byParent) != 3 || !reflect.DeepEqual(ids(byParent[0]), []int64{a, b}) ||
+		!reflect.DeepEqual(ids(byParent[a]), []int64{comments["ra1"].ID, comments["ra2"].ID}) {
+		t.Errorf("FetchCommentsByURI() = %v", byParent)
+	}
+	byParent, err = s.FetchCommentsByURI(ctx, "/post/", 0, isso.ModePublic, "id", false)
+	if err != nil || len(byParent) != 1 || !reflect.DeepEqual(ids(byParent[0]), []int64{b, a}) {
+		t.Errorf("FetchCommentsByURI() of top comments = %v, %v", byParent, err)
+	}
+	byParent, err = s.FetchCommentsByURI(ctx, "/post/", a, isso.ModePublic, "id", false)
+	if err != nil || len(byParent) != 1 || !reflect.DeepEqual(ids(byParent[a]), []int64{comments["ra2"].ID, comments["ra1"].ID}) {
+		t.Errorf("FetchCommentsByURI() of replies = %v, %v", byParent, err)
+	}
+
+	count, err := s.CountComment(ctx, []string{"/post/", "/other/", "/none/"})
+	if err != nil {
+		t.Fatalf("CountComment() error = %v", err)
+	}
+	if want := map[string]int64{"/post/": 5, "/other/": 1, "/none/": 0}; !reflect.DeepEqual(count, want) {
+		t.Errorf("CountComment() = %v, want %v", count, want)
+	}
+}
+
+func testEditComment(t *testing.T, s isso.Storage) {
+	_, comments := newThread(t, s, "/post/", "a")
+	c := comments["a"]
+	c.Text, c.Author, c.Website = "edited", "bob", strptr("https://example.com")
+	if _, err := s.EditComment(ctx, c); !errors.Is(err, isso.ErrInvalidParam) {
+		t.Errorf("EditComment() without modified error = %v, want %v", err, isso.ErrInvalidParam)
+	}
+	modified := c.Created + 1
+	c.Modified = &modified
+	got, err := s.EditComment(ctx, c)
+	if err != nil || !reflect.DeepEqual(got, c) {
+		t.Errorf("EditComment() = %+v, %v, want %+v", got, err, c)
+	}
+	c.ID += 100
+	if _, err := s.EditComment(ctx, c); !errors.Is(err, isso.ErrNotExpectAmount) {
+		t.Errorf("EditComment() of missing comment error = %v, want %v", err, isso.ErrNotExpectAmount)
+	}
+}
+
+func testDeleteComment(t *testing.T, s isso.Storage) {
+	_, comments := newThread(t, s, "/post/", "a", "ra", "b")
+	a, ra, b := comments["a"], comments["ra"], comments["b"]
+
+	// a comment with replies is kept but emptied
+	deleted, err := s.DeleteComment(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("DeleteComment() error = %v", err)
+	}
+	if deleted.ID != a.ID || deleted.Mode != isso.ModeDeleted || deleted.Text != "" ||
+		deleted.Author != "" || deleted.Website != nil {
+		t.Errorf("DeleteComment() with replies = %+v", deleted)
+	}
+	// otherwise it is removed
+	if deleted, err := s.DeleteComment(ctx, b.ID); err != nil || deleted.ID != 0 {
+		t.Errorf("DeleteComment() without replies = %+v, %v", deleted, err)
+	}
+	if _, err := s.GetComment(ctx, b.ID); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("GetComment() of deleted comment error = %v", err)
+	}
+	// and removes the deleted parent without other replies
+	if _, err := s.DeleteComment(ctx, ra.ID); err != nil {
+		t.Fatalf("DeleteComment() error = %v", err)
+	}
+	if _, err := s.GetComment(ctx, a.ID); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("GetComment() of deleted parent error = %v", err)
+	}
+	// and the thread without comments
+	if _, err := s.GetThreadByURI(ctx, "/post/"); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("GetThreadByURI() of thread without comments error = %v", err)
+	}
+}
+
+func testNewCommentGuard(t *testing.T, s isso.Storage) {
+	_, comments := newThread(t, s, "/post/", "a", "b")
+	c := isso.Comment{RemoteAddr: "10.0.0.1"}
+
+	if ok, reason := s.NewCommentGuard(ctx, c, "/post/", 2, 2, true, 900); !ok {
+		t.Errorf("NewCommentGuard() = %s", reason)
+	}
+	if ok, _ := s.NewCommentGuard(ctx, c, "/post/", 1, 2, true, 900); ok {
+		t.Errorf("NewCommentGuard() should deny 2 comments in 60s with ratelimit 1")
+	}
+	if ok, _ := s.NewCommentGuard(ctx, c, "/post/", 2, 1, true, 900); ok {
+		t.Errorf("NewCommentGuard() should deny 2 direct comments with direct-reply 1")
+	}
+	if ok, reason := s.NewCommentGuard(ctx, isso.Comment{RemoteAddr: "10.0.0.2"}, "/post/", 0, 0, true, 900); !ok {
+		t.Errorf("NewCommentGuard() of another address = %s", reason)
+	}
+
+	parent := comments["a"].ID
+	c.Parent = &parent
+	if ok, reason := s.NewCommentGuard(ctx, c, "/post/", 2, 0, true, 900); !ok {
+		t.Errorf("NewCommentGuard() of reply = %s", reason)
+	}
+	if ok, _ := s.NewCommentGuard(ctx, c, "/post/", 2, 0, false, 900); ok {
+		t.Errorf("NewCommentGuard() should deny reply to self in max-age")
+	}
+	if ok, reason := s.NewCommentGuard(ctx, c, "/post/", 2, 0, false, 0); !ok {
+		t.Errorf("NewCommentGuard() of reply to self after max-age = %s", reason)
+	}
+}
+
+func testModeMask(t *testing.T, s isso.Storage) {
+	thread, comments := newThread(t, s, "/post/", "a", "d", "rd")
+	m, err := s.NewComment(ctx, isso.Comment{Text: "m", Mode: isso.ModeModeration}, thread.ID, "10.0.0.2")
+	if err != nil {
+		t.Fatalf("NewComment() error = %v", err)
+	}
+	a, d, rd := comments["a"].ID, comments["d"].ID, comments["rd"].ID
+	if _, err := s.DeleteComment(ctx, d); err != nil {
+		t.Fatalf("DeleteComment() error = %v", err)
+	}
+
+	all := isso.ModeAccepted | isso.ModeModeration | isso.ModeDeleted
+	tests := []struct {
+		name  string
+		mode  int
+		want  []int64
+		count map[int64]int64
+	}{
+		{"accepted", isso.ModeAccepted, []int64{a, rd}, map[int64]int64{0: 1, d: 1}},
+		{"moderation", isso.ModeModeration, []int64{m.ID}, map[int64]int64{0: 1}},
+		{"deleted", isso.ModeDeleted, []int64{d}, map[int64]int64{0: 1}},
+		{"public", isso.ModePublic, []int64{a, d, rd}, map[int64]int64{0: 2, d: 1}},
+		{"all", all, []int64{a, d, m.ID, rd}, map[int64]int64{0: 3, d: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			byParent, err := s.FetchCommentsByURI(ctx, "/post/", -1, tt.mode, "id", true)
+			if err != nil {
+				t.Fatalf("FetchCommentsByURI() error = %v", err)
+			}
+			var got []int64
+			for _, parent := range []int64{0, d} {
+				for _, c := range byParent[parent] {
+					got = append(got, c.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FetchCommentsByURI() = %v, want %v", got, tt.want)
+			}
+			count, err := s.CountReply(ctx, "/post/", tt.mode, 0)
+			if err != nil || !reflect.DeepEqual(count, tt.count) {
+				t.Errorf("CountReply() = %v, %v, want %v", count, err, tt.count)
+			}
+		})
+	}
+}
+
+func testOrderBy(t *testing.T, s isso.Storage) {
+	_, comments := newThread(t, s, "/post/", "a", "b", "c")
+	a, b, c := comments["a"].ID, comments["b"].ID, comments["c"].ID
+	for i, voter := range []string{"10.0.1.1", "10.0.1.2"} {
+		if _, _, err := s.VoteComment(ctx, b, voter, true); err != nil {
+			t.Fatalf("VoteComment() error = %v", err)
+		}
+		if i == 0 {
+			if _, _, err := s.VoteComment(ctx, c, voter, true); err != nil {
+				t.Fatalf("VoteComment() error = %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		orderBy string
+		asc     bool
+		want    []int64
+	}{
+		{"id", false, []int64{c, b, a}},
+		{"created", true, []int64{a, b, c}},
+		{"likes", false, []int64{b, c, a}},
+		{"text; DROP TABLE comments", true, []int64{a, b, c}},
+	}
+	for _, tt := range tests {
+		byParent, err := s.FetchCommentsByURI(ctx, "/post/", 0, isso.ModePublic, tt.orderBy, tt.asc)
+		if err != nil {
+			t.Fatalf("FetchCommentsByURI(%s) error = %v", tt.orderBy, err)
+		}
+		var got []int64
+		for _, c := range byParent[0] {
+			got = append(got, c.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FetchCommentsByURI(%s, %v) = %v, want %v", tt.orderBy, tt.asc, got, tt.want)
+		}
+	}
+}
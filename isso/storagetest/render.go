@@ -0,0 +1,54 @@
+package storagetest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func testRendered(t *testing.T, s isso.Storage) {
+	_, comments := newThread(t, s, "/post/", "a", "ra", "b")
+	newThread(t, s, "/about/", "c")
+	a, ra, b := comments["a"].ID, comments["ra"].ID, comments["b"].ID
+
+	if got, err := s.GetRendered(ctx, "/post/"); err != nil || len(got) != 0 {
+		t.Errorf("GetRendered() without rendered = %v, %v", got, err)
+	}
+	if err := s.SetRendered(ctx, b+100, isso.Rendered{}); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("SetRendered() of missing comment error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+	want := map[int64]isso.Rendered{
+		a:  {Renderer: "v1", Checksum: "a", HTML: "<p>a</p>"},
+		ra: {Renderer: "v1", Checksum: "ra", HTML: "<p>ra</p>"},
+		b:  {Renderer: "v1", Checksum: "b", HTML: "<p>b</p>"},
+	}
+	for id, r := range want {
+		if err := s.SetRendered(ctx, id, r); err != nil {
+			t.Fatalf("SetRendered() error = %v", err)
+		}
+	}
+	want[b] = isso.Rendered{Renderer: "v2", Checksum: "b", HTML: "<p><em>b</em></p>"}
+	if err := s.SetRendered(ctx, b, want[b]); err != nil {
+		t.Fatalf("SetRendered() again error = %v", err)
+	}
+	if got, err := s.GetRendered(ctx, "/post/"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRendered() = %v, %v, want %v", got, err, want)
+	}
+	if got, err := s.GetRendered(ctx, "/about/"); err != nil || len(got) != 0 {
+		t.Errorf("GetRendered() of another thread = %v, %v", got, err)
+	}
+
+	// a is soft deleted, its html is removed as it still shows the text
+	for _, id := range []int64{a, b} {
+		if _, err := s.DeleteComment(ctx, id); err != nil {
+			t.Fatalf("DeleteComment() error = %v", err)
+		}
+	}
+	delete(want, a)
+	delete(want, b)
+	if got, err := s.GetRendered(ctx, "/post/"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRendered() after DeleteComment() = %v, %v, want %v", got, err, want)
+	}
+}
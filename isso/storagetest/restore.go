@@ -0,0 +1,129 @@
+package storagetest
+
+import (
+	"reflect"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func testRestore(t *testing.T, s isso.Storage) {
+	thread, err := s.RestoreThread(ctx, isso.Thread{ID: 42, URI: "/imported/", Title: "imported"})
+	if err != nil || thread.ID != 42 {
+		t.Fatalf("RestoreThread() = %+v, %v", thread, err)
+	}
+	auto, err := s.RestoreThread(ctx, isso.Thread{URI: "/auto/", Title: "auto"})
+	if err != nil || auto.ID == 0 || auto.ID == 42 {
+		t.Fatalf("RestoreThread() without id = %+v, %v", auto, err)
+	}
+	if next, err := s.NewThread(ctx, "/next/", "next"); err != nil || next.ID <= 42 {
+		t.Errorf("NewThread() after restored id 42 = %+v, %v", next, err)
+	}
+
+	modified := 1500000100.5
+	top := isso.Comment{ID: 100, Created: 1500000000.5, Modified: &modified, Mode: isso.ModeDeleted,
+		Text: "", Author: "", Email: strptr("alice@example.com"), Likes: 3, Dislikes: 1,
+		Notification: 1, RemoteAddr: "10.0.0.1"}
+	top.Voters[0] = 0xff
+	got, err := s.RestoreComment(ctx, top, thread.ID)
+	if err != nil || !reflect.DeepEqual(got, top) {
+		t.Fatalf("RestoreComment() = %+v, %v, want %+v", got, err, top)
+	}
+	reply := isso.Comment{ID: 101, Parent: &top.ID, Created: 1500000001, Mode: isso.ModeModeration,
+		Text: "reply", Author: "bob", Website: strptr("https://example.com"), RemoteAddr: "10.0.0.2"}
+	if got, err := s.RestoreComment(ctx, reply, thread.ID); err != nil || !reflect.DeepEqual(got, reply) {
+		t.Fatalf("RestoreComment() = %+v, %v, want %+v", got, err, reply)
+	}
+	// a reply to reply is kept as it is
+	nested := isso.Comment{ID: 102, Parent: &reply.ID, Created: 1500000002, Mode: isso.ModeAccepted,
+		Text: "nested", RemoteAddr: "10.0.0.3"}
+	if got, err := s.RestoreComment(ctx, nested, thread.ID); err != nil || *got.Parent != reply.ID {
+		t.Fatalf("RestoreComment() = %+v, %v", got, err)
+	}
+	if got, err := s.RestoreComment(ctx, isso.Comment{Created: 1500000003, Mode: isso.ModeAccepted,
+		Text: "auto", RemoteAddr: "10.0.0.4"}, thread.ID); err != nil || got.ID == 0 {
+		t.Fatalf("RestoreComment() without id = %+v, %v", got, err)
+	}
+	next, err := s.NewComment(ctx, isso.Comment{Text: "next", Mode: isso.ModeAccepted}, thread.ID, "10.0.0.5")
+	if err != nil || next.ID <= nested.ID {
+		t.Errorf("NewComment() after restored id %d = %+v, %v", nested.ID, next, err)
+	}
+
+	comments, err := s.ListComments(ctx, thread.ID)
+	if err != nil {
+		t.Fatalf("ListComments() error = %v", err)
+	}
+	var ids []int64
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	if len(ids) != 5 || ids[0] != 100 || ids[1] != 101 || ids[2] != 102 || ids[4] != next.ID {
+		t.Errorf("ListComments() ids = %v", ids)
+	}
+	if comments, err := s.ListComments(ctx, auto.ID); err != nil || len(comments) != 0 {
+		t.Errorf("ListComments() of thread without comments = %v, %v", comments, err)
+	}
+}
+
+func testRestoreVotes(t *testing.T, s isso.Storage) {
+	thread, c := newThread(t, s, "/votes/", "a", "b")
+	other, oc := newThread(t, s, "/other/", "o")
+
+	votes := []isso.Vote{
+		{CommentID: c["b"].ID, Voter: "voter1", Value: -1, Created: 1500000002},
+		{CommentID: c["a"].ID, Voter: "voter2", Value: 0, Created: 1500000001},
+		{CommentID: c["a"].ID, Voter: "voter1", Value: 1, Created: 1500000000},
+		{CommentID: oc["o"].ID, Voter: "voter1", Value: 1, Created: 1500000003},
+	}
+	for _, v := range votes {
+		if err := s.RestoreVote(ctx, v); err != nil {
+			t.Fatalf("RestoreVote(%+v) error = %v", v, err)
+		}
+	}
+	if err := s.RestoreVote(ctx, votes[0]); err == nil {
+		t.Errorf("RestoreVote() of a restored vote succeeded")
+	}
+	got, err := s.ListVotes(ctx, thread.ID)
+	if want := []isso.Vote{votes[2], votes[1], votes[0]}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ListVotes() = %+v, %v, want %+v", got, err, want)
+	}
+	// likes and dislikes are restored with the comment
+	if a, err := s.GetComment(ctx, c["a"].ID); err != nil || a.Likes != 0 || a.Dislikes != 0 {
+		t.Errorf("GetComment() after RestoreVote() = %+v, %v", a, err)
+	}
+
+	if _, err := s.React(ctx, c["a"].ID, "10.0.2.1", "heart"); err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+	reactions, err := s.ListReactions(ctx, thread.ID)
+	if err != nil || len(reactions) != 1 {
+		t.Fatalf("ListReactions() = %+v, %v", reactions, err)
+	}
+	if r := reactions[0]; r.CommentID != c["a"].ID || r.Reaction != "heart" || r.Voter == "10.0.2.1" || r.Created == 0 {
+		t.Errorf("ListReactions() = %+v, want a hashed voter", r)
+	}
+	if err := s.RestoreReaction(ctx, reactions[0]); err == nil {
+		t.Errorf("RestoreReaction() of a given reaction succeeded")
+	}
+	restored := []isso.Reaction{
+		{CommentID: c["b"].ID, Voter: "voter1", Reaction: "heart", Created: 1500000000},
+		{CommentID: c["a"].ID, Voter: reactions[0].Voter, Reaction: "laugh", Created: 1500000001},
+		{CommentID: oc["o"].ID, Voter: "voter1", Reaction: "heart", Created: 1500000002},
+	}
+	for _, r := range restored {
+		if err := s.RestoreReaction(ctx, r); err != nil {
+			t.Fatalf("RestoreReaction(%+v) error = %v", r, err)
+		}
+	}
+	// the restored voter is the one who reacted
+	if _, err := s.Unreact(ctx, c["a"].ID, "10.0.2.1", "laugh"); err != nil {
+		t.Errorf("Unreact() of restored reaction error = %v", err)
+	}
+	left, err := s.ListReactions(ctx, thread.ID)
+	if want := []isso.Reaction{reactions[0], restored[0]}; err != nil || !reflect.DeepEqual(left, want) {
+		t.Errorf("ListReactions() = %+v, %v, want %+v", left, err, want)
+	}
+	if got, err := s.ListReactions(ctx, other.ID); err != nil || len(got) != 1 {
+		t.Errorf("ListReactions() of other thread = %+v, %v", got, err)
+	}
+}
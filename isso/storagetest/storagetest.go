@@ -0,0 +1,143 @@
+// Package storagetest is a conformance test suite of isso.Storage, it specifies
+// what the SQL of database.Database does: mode bit masks, soft and hard delete,
+// removal of stale threads, reply counts keyed by parent and so on.
+// Every implementation is expected to pass it, e.g.
+//
+//	func TestStorage(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) isso.Storage { return memory.New() })
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// NewStorage return an empty isso.Storage, it is called once for every test.
+type NewStorage func(t *testing.T) isso.Storage
+
+// Run run all tests of the suite against storages created by newStorage.
+func Run(t *testing.T, newStorage NewStorage) {
+	tests := []struct {
+		name string
+		test func(*testing.T, isso.Storage)
+	}{
+		{"Thread", testThread},
+		{"NewComment", testNewComment},
+		{"FetchComments", testFetchComments},
+		{"ModeMask", testModeMask},
+		{"OrderBy", testOrderBy},
+		{"EditComment", testEditComment},
+		{"DeleteComment", testDeleteComment},
+		{"Restore", testRestore},
+		{"RestoreVotes", testRestoreVotes},
+		{"Vote", testVote},
+		{"ConcurrentVote", testConcurrentVote},
+		{"ExactVote", testExactVote},
+		{"Reaction", testReaction},
+		{"Rendered", testRendered},
+		{"Preference", testPreference},
+		{"NewCommentGuard", testNewCommentGuard},
+		{"WithTx", testWithTx},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+var ctx = context.Background()
+
+func strptr(s string) *string { return &s }
+
+// newThread create thread uri with comments, text of a comment is its name,
+// a name starting with "r" is a reply to the previous comment without "r".
+func newThread(t *testing.T, s isso.Storage, uri string, names ...string) (isso.Thread, map[string]isso.Comment) {
+	t.Helper()
+	thread, err := s.NewThread(ctx, uri, "title of "+uri)
+	if err != nil {
+		t.Fatalf("NewThread(%s) error = %v", uri, err)
+	}
+	comments := map[string]isso.Comment{}
+	var parent *int64
+	for _, name := range names {
+		c := isso.Comment{Text: name, Author: "author " + name, Mode: isso.ModeAccepted}
+		if name[0] == 'r' {
+			c.Parent = parent
+		}
+		saved, err := s.NewComment(ctx, c, thread.ID, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("NewComment(%s) error = %v", name, err)
+		}
+		if name[0] != 'r' {
+			parent = &saved.ID
+		}
+		comments[name] = saved
+	}
+	return thread, comments
+}
+
+func testThread(t *testing.T, s isso.Storage) {
+	if _, err := s.GetThreadByURI(ctx, "/"); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("GetThreadByURI() error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+	if _, err := s.GetThreadByID(ctx, 1); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("GetThreadByID() error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+	if _, err := s.NewThread(ctx, "/", ""); !errors.Is(err, isso.ErrInvalidParam) {
+		t.Errorf("NewThread() without title error = %v, want %v", err, isso.ErrInvalidParam)
+	}
+
+	thread, err := s.NewThread(ctx, "/post/", "post")
+	if err != nil {
+		t.Fatalf("NewThread() error = %v", err)
+	}
+	if thread.ID == 0 || thread.URI != "/post/" || thread.Title != "post" {
+		t.Errorf("NewThread() = %+v", thread)
+	}
+	if _, err := s.NewThread(ctx, "/post/", "again"); err == nil {
+		t.Errorf("NewThread() with existing uri should fail")
+	}
+	if got, err := s.GetThreadByURI(ctx, "/post/"); err != nil || !reflect.DeepEqual(got, thread) {
+		t.Errorf("GetThreadByURI() = %+v, %v, want %+v", got, err, thread)
+	}
+	if got, err := s.GetThreadByID(ctx, thread.ID); err != nil || !reflect.DeepEqual(got, thread) {
+		t.Errorf("GetThreadByID() = %+v, %v, want %+v", got, err, thread)
+	}
+
+	about, err := s.NewThread(ctx, "/about/", "about")
+	if err != nil {
+		t.Fatalf("NewThread() error = %v", err)
+	}
+	threads, err := s.ListThreads(ctx)
+	if err != nil || !reflect.DeepEqual(threads, []isso.Thread{thread, about}) {
+		t.Errorf("ListThreads() = %+v, %v, want %+v", threads, err, []isso.Thread{thread, about})
+	}
+}
+
+func testPreference(t *testing.T, s isso.Storage) {
+	if _, err := s.GetPreference("session-key"); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("GetPreference() error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+	if err := s.SetPreference("session-key", "secret"); err != nil {
+		t.Fatalf("SetPreference() error = %v", err)
+	}
+	if err := s.SetPreference("session-key", "another"); err == nil {
+		t.Errorf("SetPreference() of existing key should fail")
+	}
+	if got, err := s.GetPreference("session-key"); err != nil || got != "secret" {
+		t.Errorf("GetPreference() = %s, %v", got, err)
+	}
+	if err := s.SetPreference("hask-key", "hash"); err != nil {
+		t.Fatalf("SetPreference() error = %v", err)
+	}
+	want := map[string]string{"session-key": "secret", "hask-key": "hash"}
+	if got, err := s.ListPreferences(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPreferences() = %v, %v, want %v", got, err, want)
+	}
+}
@@ -0,0 +1,48 @@
+package storagetest
+
+import (
+	"errors"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+func testWithTx(t *testing.T, s isso.Storage) {
+	rollback := errors.New("rollback")
+	err := s.WithTx(ctx, func(s isso.Storage) error {
+		thread, err := s.NewThread(ctx, "/rollback/", "rollback")
+		if err != nil {
+			return err
+		}
+		// nested call joins the outer transaction
+		return s.WithTx(ctx, func(s isso.Storage) error {
+			if _, err := s.NewComment(ctx, isso.Comment{Text: "gone", Mode: isso.ModeAccepted}, thread.ID, "10.0.0.1"); err != nil {
+				return err
+			}
+			return rollback
+		})
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTx() error = %v, want %v", err, rollback)
+	}
+	if _, err := s.GetThreadByURI(ctx, "/rollback/"); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("thread of rolled back transaction error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+
+	var id int64
+	err = s.WithTx(ctx, func(s isso.Storage) error {
+		thread, err := s.NewThread(ctx, "/commit/", "commit")
+		if err != nil {
+			return err
+		}
+		c, err := s.NewComment(ctx, isso.Comment{Text: "kept", Mode: isso.ModeAccepted}, thread.ID, "10.0.0.1")
+		id = c.ID
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	if _, err := s.GetComment(ctx, id); err != nil {
+		t.Errorf("comment of committed transaction error = %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package storagetest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/tool/bloomfilter"
+)
+
+func testVote(t *testing.T, s isso.Storage) {
+	_, comments := newThread(t, s, "/post/", "a")
+	a := comments["a"].ID
+
+	tests := []struct {
+		name         string
+		voter        string
+		up           bool
+		wantLikes    int
+		wantDislikes int
+		wantErr      error
+	}{
+		{"like", "10.0.1.1", true, 1, 0, nil},
+		{"like again", "10.0.1.1", true, 1, 0, isso.ErrAlreadyVoted},
+		{"author", "10.0.0.1", true, 1, 0, isso.ErrAlreadyVoted},
+		{"dislike", "10.0.1.2", false, 1, 1, nil},
+	}
+	for _, tt := range tests {
+		likes, dislikes, err := s.VoteComment(ctx, a, tt.voter, tt.up)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("VoteComment(%s) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if likes != tt.wantLikes || dislikes != tt.wantDislikes {
+			t.Errorf("VoteComment(%s) = %d, %d, want %d, %d", tt.name, likes, dislikes, tt.wantLikes, tt.wantDislikes)
+		}
+	}
+	if _, _, err := s.VoteComment(ctx, a+100, "10.0.1.1", true); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("VoteComment() of missing comment error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+	if c, err := s.GetComment(ctx, a); err != nil || c.Likes != 1 || c.Dislikes != 1 {
+		t.Errorf("GetComment() after voting = %+v, %v", c, err)
+	}
+	if _, _, err := s.UnvoteComment(ctx, a, "10.0.1.3", true); !errors.Is(err, isso.ErrNotVoted) &&
+		!errors.Is(err, isso.ErrNotSupported) {
+		t.Errorf("UnvoteComment() without vote error = %v", err)
+	}
+}
+
+func testConcurrentVote(t *testing.T, s isso.Storage) {
+	const voters = 100
+	_, comments := newThread(t, s, "/post/", "a")
+	a := comments["a"].ID
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2*voters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// every voter votes twice
+			_, _, err := s.VoteComment(ctx, a, fmt.Sprintf("10.1.0.%d", i%voters), i%voters%2 == 0)
+			if err != nil && !errors.Is(err, isso.ErrAlreadyVoted) {
+				t.Errorf("VoteComment() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if c, err := s.GetComment(ctx, a); err != nil || c.Likes != voters/2 || c.Dislikes != voters/2 {
+		t.Errorf("GetComment() after %d concurrent voters = %+v, %v", voters, c, err)
+	}
+}
+
+// testExactVote runs if the storage supports UnvoteComment.
+func testExactVote(t *testing.T, s isso.Storage) {
+	thread, comments := newThread(t, s, "/post/", "a")
+	a := comments["a"].ID
+	if _, _, err := s.UnvoteComment(ctx, a, "10.0.1.1", true); errors.Is(err, isso.ErrNotSupported) {
+		t.Skip("storage does not support UnvoteComment")
+	}
+
+	tests := []struct {
+		name         string
+		unvote       bool
+		up           bool
+		wantLikes    int
+		wantDislikes int
+		wantErr      error
+	}{
+		{"like", false, true, 1, 0, nil},
+		{"switch to dislike", false, false, 0, 1, nil},
+		{"unvote like", true, true, 0, 1, isso.ErrNotVoted},
+		{"unvote dislike", true, false, 0, 0, nil},
+		{"like after unvote", false, true, 1, 0, nil},
+	}
+	for _, tt := range tests {
+		vote := s.VoteComment
+		if tt.unvote {
+			vote = s.UnvoteComment
+		}
+		likes, dislikes, err := vote(ctx, a, "10.0.1.1", tt.up)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if likes != tt.wantLikes || dislikes != tt.wantDislikes {
+			t.Errorf("%s = %d, %d, want %d, %d", tt.name, likes, dislikes, tt.wantLikes, tt.wantDislikes)
+		}
+	}
+	// exact voters are in the bloom filter too, so they are known if exact votes are disabled
+	if c, err := s.GetComment(ctx, a); err != nil || !bloomfilter.RecoverFrom(c.Voters, 0).Contains([]byte("10.0.1.1")) {
+		t.Errorf("exact voter is not in the bloom filter, GetComment() error = %v", err)
+	}
+	for i := 0; i < isso.MaxVotes+10; i++ {
+		if _, _, err := s.VoteComment(ctx, a, fmt.Sprintf("10.2.%d.%d", i/256, i%256), true); err != nil {
+			t.Fatalf("VoteComment() of exact votes is not limited, error = %v", err)
+		}
+	}
+
+	// votes in a bloom filter before exact votes are migrated when their voters vote again,
+	// if the votes in the bloom filter are all likes or all dislikes
+	restore := func(likes, dislikes int, author string, voters ...string) int64 {
+		bf := bloomfilter.New()
+		for _, voter := range append(voters, author) {
+			bf.Add([]byte(voter))
+		}
+		c, err := s.RestoreComment(ctx, isso.Comment{Text: "legacy", Mode: isso.ModeAccepted, RemoteAddr: author,
+			Likes: likes, Dislikes: dislikes, Voters: bf.Buffer()}, thread.ID)
+		if err != nil {
+			t.Fatalf("RestoreComment() error = %v", err)
+		}
+		return c.ID
+	}
+	liked := restore(2, 0, "10.3.0.1", "10.3.0.2", "10.3.0.3")
+	mixed := restore(1, 1, "10.4.0.1", "10.4.0.2", "10.4.0.3")
+	legacy := []struct {
+		name         string
+		id           int64
+		unvote       bool
+		voter        string
+		up           bool
+		wantLikes    int
+		wantDislikes int
+		wantErr      error
+	}{
+		{"author", liked, false, "10.3.0.1", true, 2, 0, isso.ErrAlreadyVoted},
+		{"author unvote", liked, true, "10.3.0.1", true, 2, 0, isso.ErrNotVoted},
+		{"like again", liked, false, "10.3.0.2", true, 2, 0, isso.ErrAlreadyVoted},
+		{"switch to dislike", liked, false, "10.3.0.2", false, 1, 1, nil},
+		{"unvote like", liked, true, "10.3.0.3", true, 0, 1, nil},
+		{"like after unvote", liked, false, "10.3.0.3", true, 1, 1, nil},
+		{"new voter", liked, false, "10.3.0.4", true, 2, 1, nil},
+		{"mixed", mixed, false, "10.4.0.2", false, 1, 1, isso.ErrAlreadyVoted},
+		{"mixed unvote", mixed, true, "10.4.0.2", true, 1, 1, isso.ErrNotVoted},
+	}
+	for _, tt := range legacy {
+		vote := s.VoteComment
+		if tt.unvote {
+			vote = s.UnvoteComment
+		}
+		likes, dislikes, err := vote(ctx, tt.id, tt.voter, tt.up)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("bloom filter %s error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if likes != tt.wantLikes || dislikes != tt.wantDislikes {
+			t.Errorf("bloom filter %s = %d, %d, want %d, %d", tt.name, likes, dislikes, tt.wantLikes, tt.wantDislikes)
+		}
+	}
+}
+
+func testReaction(t *testing.T, s isso.Storage) {
+	_, comments := newThread(t, s, "/post/", "a", "b")
+	a, b := comments["a"].ID, comments["b"].ID
+
+	tests := []struct {
+		name     string
+		unreact  bool
+		voter    string
+		reaction string
+		want     map[string]int
+		wantErr  error
+	}{
+		{"thumbs up", false, "10.0.1.1", "👍", map[string]int{"👍": 1}, nil},
+		{"thumbs up again", false, "10.0.1.1", "👍", nil, isso.ErrAlreadyVoted},
+		{"author", false, "10.0.0.1", "👍", nil, isso.ErrAlreadyVoted},
+		{"another type", false, "10.0.1.1", "🎉", map[string]int{"👍": 1, "🎉": 1}, nil},
+		{"another voter", false, "10.0.1.2", "👍", map[string]int{"👍": 2, "🎉": 1}, nil},
+		{"unreact", true, "10.0.1.1", "🎉", map[string]int{"👍": 2}, nil},
+		{"unreact again", true, "10.0.1.1", "🎉", nil, isso.ErrNotVoted},
+	}
+	for _, tt := range tests {
+		react := s.React
+		if tt.unreact {
+			react = s.Unreact
+		}
+		got, err := react(ctx, a, tt.voter, tt.reaction)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if _, err := s.React(ctx, a+100, "10.0.1.1", "👍"); !errors.Is(err, isso.ErrStorageNotFound) {
+		t.Errorf("React() to missing comment error = %v, want %v", err, isso.ErrStorageNotFound)
+	}
+	if _, err := s.React(ctx, b, "10.0.1.1", "❤️"); err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+
+	want := map[int64]map[string]int{a: {"👍": 2}, b: {"❤️": 1}}
+	if got, err := s.CountReactions(ctx, "/post/"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("CountReactions() = %v, %v, want %v", got, err, want)
+	}
+	if got, err := s.GetReactions(ctx, b); err != nil || !reflect.DeepEqual(got, want[b]) {
+		t.Errorf("GetReactions() = %v, %v, want %v", got, err, want[b])
+	}
+
+	if ok, _ := s.ReactionGuard(ctx, "10.0.1.1", 1); ok {
+		t.Errorf("ReactionGuard() should deny 2 reactions in 60s with ratelimit 1")
+	}
+	if ok, reason := s.ReactionGuard(ctx, "10.0.1.2", 1); !ok {
+		t.Errorf("ReactionGuard() = %s", reason)
+	}
+
+	// reactions are removed with the comment
+	if _, err := s.DeleteComment(ctx, b); err != nil {
+		t.Fatalf("DeleteComment() error = %v", err)
+	}
+	if got, err := s.GetReactions(ctx, b); err != nil || len(got) != 0 {
+		t.Errorf("GetReactions() of deleted comment = %v, %v", got, err)
+	}
+}
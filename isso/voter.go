@@ -0,0 +1,60 @@
+package isso
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// VoterKeyPreference is the preference of the secret key voters are hashed with. It is
+// created by the first vote or reaction, and the voters saved in storage are only
+// recognized with it, so it is copied together with them.
+const VoterKeyPreference = "voter-key"
+
+// VoterHasher hash voter identities, e.g. IP addresses, before storages save them with
+// votes and reactions. The hash is keyed by VoterKeyPreference, unlike a plain hash it
+// can not be reversed by hashing every IP address.
+type VoterHasher struct {
+	mu  sync.Mutex
+	key []byte
+}
+
+// Hash return the hex encoded HMAC-SHA256 of voter, the key is read from p once
+// and created if p has none.
+func (h *VoterHasher) Hash(p PreferenceStorage, voter string) (string, error) {
+	key, err := h.loadKey(p)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(voter))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (h *VoterHasher) loadKey(p PreferenceStorage) ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.key != nil {
+		return h.key, nil
+	}
+	value, err := p.GetPreference(VoterKeyPreference)
+	if errors.Is(err, ErrStorageNotFound) {
+		var key [32]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return nil, err
+		}
+		value = hex.EncodeToString(key[:])
+		if err = p.SetPreference(VoterKeyPreference, value); err != nil {
+			// another process may have created it at the same time
+			value, err = p.GetPreference(VoterKeyPreference)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.key = []byte(value)
+	return h.key, nil
+}
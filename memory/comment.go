@@ -0,0 +1,261 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/tool/bloomfilter"
+)
+
+// IsApprovedAuthor check if email has approved in 6 month
+func (s *Storage) IsApprovedAuthor(ctx context.Context, email string) bool {
+	if email == "" {
+		return false
+	}
+	defer s.lock()()
+	after := float64(time.Now().AddDate(0, -6, 0).Unix())
+	for _, c := range s.data.comments {
+		if c.Email != nil && *c.Email == email && c.Mode == isso.ModeAccepted && c.Created > after {
+			return true
+		}
+	}
+	return false
+}
+
+// NewComment add comment, a reply to a reply is saved as a reply to the top comment.
+func (s *Storage) NewComment(ctx context.Context, c isso.Comment, threadID int64, remoteAddr string) (isso.Comment, error) {
+	defer s.lock()()
+	if c.Parent != nil {
+		parent, ok := s.data.comments[*c.Parent]
+		if !ok {
+			return isso.Comment{}, isso.ErrStorageNotFound
+		}
+		if parent.tid != threadID {
+			return isso.Comment{}, isso.ErrInvalidParam
+		}
+		if parent.Parent != nil {
+			c.Parent = parent.Parent
+		}
+	}
+
+	bf := bloomfilter.New()
+	bf.Add([]byte(remoteAddr))
+
+	c = copyComment(c)
+	c.ID = s.data.lastCommentID + 1
+	c.Created = now()
+	c.Modified = nil
+	c.RemoteAddr = remoteAddr
+	c.Voters = bf.Buffer()
+	s.data.lastCommentID = c.ID
+	s.data.comments[c.ID] = comment{c, threadID}
+	return copyComment(c), nil
+}
+
+// GetComment get comment by ID
+func (s *Storage) GetComment(ctx context.Context, id int64) (isso.Comment, error) {
+	defer s.lock()()
+	c, ok := s.data.comments[id]
+	if !ok {
+		return isso.Comment{}, isso.ErrStorageNotFound
+	}
+	return copyComment(c.Comment), nil
+}
+
+// CountReply return comment count for main thread's comment and all reply threads for one uri.
+// 0 mean null parent
+func (s *Storage) CountReply(ctx context.Context, uri string, mode int, after float64) (map[int64]int64, error) {
+	defer s.lock()()
+	counts := map[int64]int64{}
+	for _, c := range s.commentsOf(uri, mode) {
+		if c.Created <= after {
+			continue
+		}
+		if c.Parent != nil {
+			counts[*c.Parent]++
+		} else {
+			counts[0]++
+		}
+	}
+	return counts, nil
+}
+
+// FetchCommentsByURI fetch comments related uri with a lot of param
+func (s *Storage) FetchCommentsByURI(ctx context.Context, uri string, parent int64, mode int, orderBy string, asc bool) (map[int64][]isso.Comment, error) {
+	defer s.lock()()
+	var comments []isso.Comment
+	for _, c := range s.commentsOf(uri, mode) {
+		switch {
+		case parent == 0 && c.Parent != nil:
+			continue
+		case parent > 0 && (c.Parent == nil || *c.Parent != parent):
+			continue
+		}
+		comments = append(comments, copyComment(c.Comment))
+	}
+
+	less := commentLess(orderBy)
+	sort.SliceStable(comments, func(i, j int) bool {
+		if asc {
+			return less(comments[i], comments[j])
+		}
+		return less(comments[j], comments[i])
+	})
+
+	commentsbyparent := map[int64][]isso.Comment{}
+	for _, c := range comments {
+		if c.Parent != nil {
+			commentsbyparent[*c.Parent] = append(commentsbyparent[*c.Parent], c)
+		} else {
+			commentsbyparent[0] = append(commentsbyparent[0], c)
+		}
+	}
+	return commentsbyparent, nil
+}
+
+// commentLess compare comments by column orderBy, NULL modified is the least as in SQL.
+func commentLess(orderBy string) func(a, b isso.Comment) bool {
+	switch orderBy {
+	case "created":
+		return func(a, b isso.Comment) bool { return a.Created < b.Created }
+	case "modified":
+		return func(a, b isso.Comment) bool {
+			if a.Modified == nil || b.Modified == nil {
+				return a.Modified == nil && b.Modified != nil
+			}
+			return *a.Modified < *b.Modified
+		}
+	case "likes":
+		return func(a, b isso.Comment) bool { return a.Likes < b.Likes }
+	case "dislikes":
+		return func(a, b isso.Comment) bool { return a.Dislikes < b.Dislikes }
+	default:
+		return func(a, b isso.Comment) bool { return a.ID < b.ID }
+	}
+}
+
+// commentsOf return comments of uri whose mode is in mode, ordered by id.
+func (s *Storage) commentsOf(uri string, mode int) []comment {
+	t, ok := s.threadByURI(uri)
+	if !ok {
+		return nil
+	}
+	var comments []comment
+	for _, c := range s.data.comments {
+		if c.tid == t.ID && mode|c.Mode == mode {
+			comments = append(comments, c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	return comments
+}
+
+// CountComment count accepted comments per thread
+func (s *Storage) CountComment(ctx context.Context, uris []string) (map[string]int64, error) {
+	defer s.lock()()
+	counts := map[string]int64{}
+	for _, uri := range uris {
+		counts[uri] = int64(len(s.commentsOf(uri, isso.ModeAccepted)))
+	}
+	return counts, nil
+}
+
+// ActivateComment Activate comment id if pending
+func (s *Storage) ActivateComment(ctx context.Context, id int64) error {
+	defer s.lock()()
+	c, ok := s.data.comments[id]
+	if !ok || c.Mode != isso.ModeModeration {
+		return isso.ErrNotExpectAmount
+	}
+	c.Mode = isso.ModeAccepted
+	s.data.comments[id] = c
+	return nil
+}
+
+// EditComment edit text, author, website, email and modified of comment
+func (s *Storage) EditComment(ctx context.Context, c isso.Comment) (isso.Comment, error) {
+	if c.Modified == nil {
+		return isso.Comment{}, isso.ErrInvalidParam
+	}
+	defer s.lock()()
+	stored, ok := s.data.comments[c.ID]
+	if !ok {
+		return isso.Comment{}, isso.ErrNotExpectAmount
+	}
+	c = copyComment(c)
+	stored.Text, stored.Author, stored.Website, stored.Modified, stored.Email =
+		c.Text, c.Author, c.Website, c.Modified, c.Email
+	s.data.comments[c.ID] = stored
+	return copyComment(stored.Comment), nil
+}
+
+// DeleteComment delete comment by id. A comment with replies is only marked deleted,
+// and returned. Deleted comments without replies and threads without comments are removed.
+func (s *Storage) DeleteComment(ctx context.Context, cid int64) (isso.Comment, error) {
+	defer s.lock()()
+	var deleted isso.Comment
+	removed := false
+	if c, ok := s.data.comments[cid]; ok {
+		if s.hasReplies(cid) {
+			c.Mode, c.Text, c.Author, c.Website = isso.ModeDeleted, "", "", nil
+			s.data.comments[cid] = c
+			delete(s.data.rendered, cid)
+			deleted = copyComment(c.Comment)
+		} else {
+			delete(s.data.comments, cid)
+			removed = true
+		}
+	}
+	for id, c := range s.data.comments {
+		if c.Mode == isso.ModeDeleted && !s.hasReplies(id) {
+			delete(s.data.comments, id)
+			removed = true
+		}
+	}
+	if removed {
+		s.removeStaleThreads()
+		s.removeStaleVotes()
+	}
+	return deleted, nil
+}
+
+func (s *Storage) hasReplies(id int64) bool {
+	for _, c := range s.data.comments {
+		if c.Parent != nil && *c.Parent == id {
+			return true
+		}
+	}
+	return false
+}
+
+// RestoreComment save comment as it is, used by importers
+func (s *Storage) RestoreComment(ctx context.Context, c isso.Comment, threadID int64) (isso.Comment, error) {
+	defer s.lock()()
+	c = copyComment(c)
+	if c.ID == 0 {
+		c.ID = s.data.lastCommentID + 1
+	}
+	if _, ok := s.data.comments[c.ID]; ok {
+		return isso.Comment{}, isso.ErrInvalidParam
+	}
+	if c.ID > s.data.lastCommentID {
+		s.data.lastCommentID = c.ID
+	}
+	s.data.comments[c.ID] = comment{c, threadID}
+	return copyComment(c), nil
+}
+
+// ListComments return all comments of thread
+func (s *Storage) ListComments(ctx context.Context, threadID int64) ([]isso.Comment, error) {
+	defer s.lock()()
+	var comments []isso.Comment
+	for _, c := range s.data.comments {
+		if c.tid == threadID {
+			comments = append(comments, copyComment(c.Comment))
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	return comments, nil
+}
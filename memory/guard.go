@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// NewCommentGuard limit comment
+func (s *Storage) NewCommentGuard(ctx context.Context, c isso.Comment, uri string,
+	ratelimit int, directreply int, replytoself bool, maxage int) (bool, string) {
+	defer s.lock()()
+	t := now()
+
+	var n int
+	for _, stored := range s.data.comments {
+		if stored.RemoteAddr == c.RemoteAddr && t-stored.Created < 60 {
+			n++
+		}
+	}
+	if n > ratelimit {
+		return false, fmt.Sprintf("%s ratelimit exceeded: %d comments in 60s", c.RemoteAddr, n)
+	}
+
+	if c.Parent == nil {
+		n = 0
+		if thread, ok := s.threadByURI(uri); ok {
+			for _, stored := range s.data.comments {
+				if stored.tid == thread.ID && stored.RemoteAddr == c.RemoteAddr && stored.Parent == nil {
+					n++
+				}
+			}
+		}
+		if n > directreply {
+			return false, fmt.Sprintf("%d direct responses to %s", n, uri)
+		}
+	} else if !replytoself {
+		parent, ok := s.data.comments[*c.Parent]
+		if ok && parent.RemoteAddr == c.RemoteAddr && t-parent.Created < float64(maxage) {
+			return false, "edit time frame is still open"
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,146 @@
+// Package memory implements isso.Storage in memory, for embedders and tests
+// which do not want a database. Nothing is persisted.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// Storage keeps threads, comments and preferences in memory.
+// It behaves as database.Database does, and is safe for concurrent use.
+type Storage struct {
+	// ExactVotes record every voter, so votes can be changed and retracted.
+	ExactVotes bool
+
+	mu     *sync.Mutex
+	data   *data
+	voters *isso.VoterHasher
+	// inTx is true when Storage is given by WithTx, which holds mu
+	inTx bool
+}
+
+type data struct {
+	threads     map[int64]isso.Thread
+	comments    map[int64]comment
+	preferences map[string]string
+	votes       map[voteKey]vote
+	reactions   map[reactionKey]float64
+	rendered    map[int64]isso.Rendered
+
+	lastThreadID  int64
+	lastCommentID int64
+}
+
+// comment is a comment with its thread id
+type comment struct {
+	isso.Comment
+	tid int64
+}
+
+type voteKey struct {
+	id    int64
+	voter string
+}
+
+// vote is the value of a vote in votes table of database, and when it is created
+type vote struct {
+	value   int
+	created float64
+}
+
+type reactionKey struct {
+	id       int64
+	voter    string
+	reaction string
+}
+
+// New return an empty *Storage.
+func New() *Storage {
+	return &Storage{
+		mu:     &sync.Mutex{},
+		voters: &isso.VoterHasher{},
+		data: &data{
+			threads:     map[int64]isso.Thread{},
+			comments:    map[int64]comment{},
+			preferences: map[string]string{},
+			votes:       map[voteKey]vote{},
+			reactions:   map[reactionKey]float64{},
+			rendered:    map[int64]isso.Rendered{},
+		},
+	}
+}
+
+// lock s unless it is in a transaction, which has locked it.
+func (s *Storage) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+func (d *data) clone() *data {
+	c := &data{
+		threads:       make(map[int64]isso.Thread, len(d.threads)),
+		comments:      make(map[int64]comment, len(d.comments)),
+		preferences:   make(map[string]string, len(d.preferences)),
+		votes:         make(map[voteKey]vote, len(d.votes)),
+		reactions:     make(map[reactionKey]float64, len(d.reactions)),
+		rendered:      make(map[int64]isso.Rendered, len(d.rendered)),
+		lastThreadID:  d.lastThreadID,
+		lastCommentID: d.lastCommentID,
+	}
+	for k, v := range d.threads {
+		c.threads[k] = v
+	}
+	for k, v := range d.comments {
+		c.comments[k] = v
+	}
+	for k, v := range d.preferences {
+		c.preferences[k] = v
+	}
+	for k, v := range d.votes {
+		c.votes[k] = v
+	}
+	for k, v := range d.reactions {
+		c.reactions[k] = v
+	}
+	for k, v := range d.rendered {
+		c.rendered[k] = v
+	}
+	return c
+}
+
+// copyComment return c without pointers shared with the stored one.
+func copyComment(c isso.Comment) isso.Comment {
+	if c.Parent != nil {
+		parent := *c.Parent
+		c.Parent = &parent
+	}
+	if c.Modified != nil {
+		modified := *c.Modified
+		c.Modified = &modified
+	}
+	if c.Email != nil {
+		email := *c.Email
+		c.Email = &email
+	}
+	if c.Website != nil {
+		website := *c.Website
+		c.Website = &website
+	}
+	return c
+}
+
+func now() float64 {
+	return float64(time.Now().UnixNano()) / float64(1e9)
+}
+
+// hashVoter keep voter identity, e.g. the IP address, as database.Database does.
+// It must be called before s is locked, the key is a preference.
+func (s *Storage) hashVoter(voter string) (string, error) {
+	return s.voters.Hash(s, voter)
+}
@@ -0,0 +1,20 @@
+package memory
+
+import (
+	"testing"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/isso/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) isso.Storage { return New() })
+}
+
+func TestStorageExactVotes(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) isso.Storage {
+		s := New()
+		s.ExactVotes = true
+		return s
+	})
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"errors"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// errUniqueKey is returned when the preference exists, as the PRIMARY KEY constraint of database does.
+var errUniqueKey = errors.New("memory: preference key exists")
+
+// GetPreference get preference use key
+func (s *Storage) GetPreference(key string) (string, error) {
+	defer s.lock()()
+	value, ok := s.data.preferences[key]
+	if !ok {
+		return "", isso.ErrStorageNotFound
+	}
+	return value, nil
+}
+
+// SetPreference set preference with key value pairs, an existing key is not overwritten.
+func (s *Storage) SetPreference(key string, value string) error {
+	defer s.lock()()
+	if _, ok := s.data.preferences[key]; ok {
+		return errUniqueKey
+	}
+	s.data.preferences[key] = value
+	return nil
+}
+
+// ListPreferences return all preferences as key value pairs.
+func (s *Storage) ListPreferences() (map[string]string, error) {
+	defer s.lock()()
+	preferences := make(map[string]string, len(s.data.preferences))
+	for k, v := range s.data.preferences {
+		preferences[k] = v
+	}
+	return preferences, nil
+}
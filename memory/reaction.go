@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// React add reaction of voter to comment id and return reaction counts of the comment.
+func (s *Storage) React(ctx context.Context, id int64, voter string, reaction string) (map[string]int, error) {
+	hashed, err := s.hashVoter(voter)
+	if err != nil {
+		return nil, err
+	}
+	defer s.lock()()
+	c, ok := s.data.comments[id]
+	if !ok {
+		return nil, isso.ErrStorageNotFound
+	}
+	key := reactionKey{id, hashed, reaction}
+	if _, ok := s.data.reactions[key]; ok || voter == c.RemoteAddr {
+		return nil, isso.ErrAlreadyVoted
+	}
+	s.data.reactions[key] = now()
+	return s.countReactions(id), nil
+}
+
+// Unreact retract reaction of voter from comment id and return reaction counts of the comment.
+func (s *Storage) Unreact(ctx context.Context, id int64, voter string, reaction string) (map[string]int, error) {
+	hashed, err := s.hashVoter(voter)
+	if err != nil {
+		return nil, err
+	}
+	defer s.lock()()
+	if _, ok := s.data.comments[id]; !ok {
+		return nil, isso.ErrStorageNotFound
+	}
+	key := reactionKey{id, hashed, reaction}
+	if _, ok := s.data.reactions[key]; !ok {
+		return nil, isso.ErrNotVoted
+	}
+	delete(s.data.reactions, key)
+	return s.countReactions(id), nil
+}
+
+// GetReactions return reaction counts of comment id.
+func (s *Storage) GetReactions(ctx context.Context, id int64) (map[string]int, error) {
+	defer s.lock()()
+	return s.countReactions(id), nil
+}
+
+func (s *Storage) countReactions(id int64) map[string]int {
+	counts := map[string]int{}
+	for k := range s.data.reactions {
+		if k.id == id {
+			counts[k.reaction]++
+		}
+	}
+	return counts
+}
+
+// CountReactions return reaction counts of comments of uri by comment id.
+func (s *Storage) CountReactions(ctx context.Context, uri string) (map[int64]map[string]int, error) {
+	defer s.lock()()
+	counts := map[int64]map[string]int{}
+	t, ok := s.threadByURI(uri)
+	if !ok {
+		return counts, nil
+	}
+	for k := range s.data.reactions {
+		if c, ok := s.data.comments[k.id]; !ok || c.tid != t.ID {
+			continue
+		}
+		if counts[k.id] == nil {
+			counts[k.id] = map[string]int{}
+		}
+		counts[k.id][k.reaction]++
+	}
+	return counts, nil
+}
+
+// ListReactions return reactions of comments of thread, ordered by comment id, voter and reaction.
+func (s *Storage) ListReactions(ctx context.Context, threadID int64) ([]isso.Reaction, error) {
+	defer s.lock()()
+	var reactions []isso.Reaction
+	for k, created := range s.data.reactions {
+		if c, ok := s.data.comments[k.id]; ok && c.tid == threadID {
+			reactions = append(reactions, isso.Reaction{CommentID: k.id, Voter: k.voter, Reaction: k.reaction, Created: created})
+		}
+	}
+	sort.Slice(reactions, func(i, j int) bool {
+		a, b := reactions[i], reactions[j]
+		if a.CommentID != b.CommentID {
+			return a.CommentID < b.CommentID
+		}
+		if a.Voter != b.Voter {
+			return a.Voter < b.Voter
+		}
+		return a.Reaction < b.Reaction
+	})
+	return reactions, nil
+}
+
+// RestoreReaction save r as it is.
+func (s *Storage) RestoreReaction(ctx context.Context, r isso.Reaction) error {
+	defer s.lock()()
+	key := reactionKey{r.CommentID, r.Voter, r.Reaction}
+	if _, ok := s.data.reactions[key]; ok {
+		return isso.ErrInvalidParam
+	}
+	s.data.reactions[key] = r.Created
+	return nil
+}
+
+// ReactionGuard limit reactions of voter in 60s to ratelimit
+func (s *Storage) ReactionGuard(ctx context.Context, voter string, ratelimit int) (bool, string) {
+	hashed, err := s.hashVoter(voter)
+	if err != nil {
+		return false, fmt.Sprintf("hash voter failed: %v", err)
+	}
+	defer s.lock()()
+	t := now()
+	var n int
+	for k, created := range s.data.reactions {
+		if k.voter == hashed && t-created < 60 {
+			n++
+		}
+	}
+	if n > ratelimit {
+		return false, fmt.Sprintf("%s ratelimit exceeded: %d reactions in 60s", voter, n)
+	}
+	return true, ""
+}
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"context"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// SetRendered save r as the html of comment id, replacing the saved one.
+func (s *Storage) SetRendered(ctx context.Context, id int64, r isso.Rendered) error {
+	defer s.lock()()
+	if _, ok := s.data.comments[id]; !ok {
+		return isso.ErrStorageNotFound
+	}
+	s.data.rendered[id] = r
+	return nil
+}
+
+// GetRendered return the saved html of comments of uri by comment id.
+func (s *Storage) GetRendered(ctx context.Context, uri string) (map[int64]isso.Rendered, error) {
+	defer s.lock()()
+	rendered := map[int64]isso.Rendered{}
+	t, ok := s.threadByURI(uri)
+	if !ok {
+		return rendered, nil
+	}
+	for id, r := range s.data.rendered {
+		if c, ok := s.data.comments[id]; ok && c.tid == t.ID {
+			rendered[id] = r
+		}
+	}
+	return rendered, nil
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// errUniqueURI is returned when a thread of the uri exists, as the UNIQUE constraint of database does.
+var errUniqueURI = errors.New("memory: thread uri exists")
+
+// GetThreadByURI get thread by uri
+func (s *Storage) GetThreadByURI(ctx context.Context, uri string) (isso.Thread, error) {
+	defer s.lock()()
+	if t, ok := s.threadByURI(uri); ok {
+		return t, nil
+	}
+	return isso.Thread{}, isso.ErrStorageNotFound
+}
+
+// GetThreadByID get thread by id
+func (s *Storage) GetThreadByID(ctx context.Context, id int64) (isso.Thread, error) {
+	defer s.lock()()
+	if t, ok := s.data.threads[id]; ok {
+		return t, nil
+	}
+	return isso.Thread{}, isso.ErrStorageNotFound
+}
+
+// GetThreadByCommentID get the thread of comment cid
+func (s *Storage) GetThreadByCommentID(ctx context.Context, cid int64) (isso.Thread, error) {
+	defer s.lock()()
+	if c, ok := s.data.comments[cid]; ok {
+		return s.data.threads[c.tid], nil
+	}
+	return isso.Thread{}, isso.ErrStorageNotFound
+}
+
+// NewThread new a thread
+func (s *Storage) NewThread(ctx context.Context, uri string, title string) (isso.Thread, error) {
+	if title == "" || uri == "" {
+		return isso.Thread{}, isso.ErrInvalidParam
+	}
+	defer s.lock()()
+	return s.saveThread(isso.Thread{URI: uri, Title: title})
+}
+
+// RestoreThread save thread with its id, used by importers
+func (s *Storage) RestoreThread(ctx context.Context, t isso.Thread) (isso.Thread, error) {
+	if t.URI == "" {
+		return isso.Thread{}, isso.ErrInvalidParam
+	}
+	defer s.lock()()
+	return s.saveThread(t)
+}
+
+// ListThreads return all threads
+func (s *Storage) ListThreads(ctx context.Context) ([]isso.Thread, error) {
+	defer s.lock()()
+	var threads []isso.Thread
+	for _, t := range s.data.threads {
+		threads = append(threads, t)
+	}
+	sort.Slice(threads, func(i, j int) bool { return threads[i].ID < threads[j].ID })
+	return threads, nil
+}
+
+// saveThread save t, zero t.ID is replaced by a new id.
+func (s *Storage) saveThread(t isso.Thread) (isso.Thread, error) {
+	if _, ok := s.threadByURI(t.URI); ok {
+		return isso.Thread{}, errUniqueURI
+	}
+	if t.ID == 0 {
+		t.ID = s.data.lastThreadID + 1
+	}
+	if _, ok := s.data.threads[t.ID]; ok {
+		return isso.Thread{}, isso.ErrInvalidParam
+	}
+	if t.ID > s.data.lastThreadID {
+		s.data.lastThreadID = t.ID
+	}
+	s.data.threads[t.ID] = t
+	return t, nil
+}
+
+func (s *Storage) threadByURI(uri string) (isso.Thread, bool) {
+	for _, t := range s.data.threads {
+		if t.URI == uri {
+			return t, true
+		}
+	}
+	return isso.Thread{}, false
+}
+
+// removeStaleThreads remove threads without comments, as the trigger of database does.
+func (s *Storage) removeStaleThreads() {
+	used := map[int64]bool{}
+	for _, c := range s.data.comments {
+		used[c.tid] = true
+	}
+	for id := range s.data.threads {
+		if !used[id] {
+			delete(s.data.threads, id)
+		}
+	}
+}
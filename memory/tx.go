@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+
+	"wrong.wang/x/go-isso/isso"
+)
+
+// WithTx run fn with s locked, changes made by fn are reverted if fn return an error.
+// Nested calls join the outer transaction.
+func (s *Storage) WithTx(ctx context.Context, fn func(isso.Storage) error) error {
+	return s.withTx(func(tx *Storage) error { return fn(tx) })
+}
+
+func (s *Storage) withTx(fn func(*Storage) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.data.clone()
+	tx := *s
+	tx.inTx = true
+	if err := fn(&tx); err != nil {
+		*s.data = *snapshot
+		return err
+	}
+	return nil
+}
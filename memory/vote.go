@@ -0,0 +1,213 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"wrong.wang/x/go-isso/isso"
+	"wrong.wang/x/go-isso/tool/bloomfilter"
+)
+
+// vote values, as the votes table of database
+const (
+	voteLike    = 1
+	voteDislike = -1
+)
+
+// VoteComment vote comment id. Voters are remembered in the bloom filter of comment, with
+// ExactVotes they are recorded exactly too and can change the vote, otherwise a comment
+// accepts at most isso.MaxVotes votes.
+func (s *Storage) VoteComment(ctx context.Context, id int64, voter string, up bool) (int, int, error) {
+	var hashed string
+	if s.ExactVotes {
+		var err error
+		if hashed, err = s.hashVoter(voter); err != nil {
+			return 0, 0, err
+		}
+	}
+	defer s.lock()()
+	c, ok := s.data.comments[id]
+	if !ok {
+		return 0, 0, isso.ErrStorageNotFound
+	}
+	value := voteDislike
+	if up {
+		value = voteLike
+	}
+	bf := bloomfilter.RecoverFrom(c.Voters, c.Likes+c.Dislikes)
+
+	if !s.ExactVotes {
+		switch {
+		case c.Likes+c.Dislikes > isso.MaxVotes:
+			return c.Likes, c.Dislikes, isso.ErrTooManyVotes
+		case bf.Contains([]byte(voter)):
+			return c.Likes, c.Dislikes, isso.ErrAlreadyVoted
+		}
+		bf.Add([]byte(voter))
+		c.Voters = bf.Buffer()
+		addVote(&c.Comment, value, 1)
+		s.data.comments[id] = c
+		return c.Likes, c.Dislikes, nil
+	}
+
+	key := voteKey{id, hashed}
+	v, voted := s.data.votes[key]
+	old := v.value
+	var add bool
+	if !voted {
+		var err error
+		if old, add, err = s.newVoter(c, voter, bf); err != nil {
+			return c.Likes, c.Dislikes, err
+		}
+		v.created = now()
+	}
+	if old == value {
+		return c.Likes, c.Dislikes, isso.ErrAlreadyVoted
+	}
+	if add {
+		bf.Add([]byte(voter))
+		c.Voters = bf.Buffer()
+	}
+	v.value = value
+	s.data.votes[key] = v
+	if old != 0 {
+		addVote(&c.Comment, old, -1)
+	}
+	addVote(&c.Comment, value, 1)
+	s.data.comments[id] = c
+	return c.Likes, c.Dislikes, nil
+}
+
+// newVoter return the vote of voter not recorded exactly yet, and whether to add it to
+// the bloom filter, as database.Database does.
+func (s *Storage) newVoter(c comment, voter string, bf *bloomfilter.Bloomfilter) (int, bool, error) {
+	if !bf.Contains([]byte(voter)) {
+		return 0, c.Likes+c.Dislikes < isso.MaxVotes, nil
+	}
+	// the author is in the bloom filter without a vote
+	if voter == c.RemoteAddr {
+		return 0, false, isso.ErrAlreadyVoted
+	}
+	var exactLikes, exactDislikes int
+	for k, v := range s.data.votes {
+		if k.id == c.ID && v.value == voteLike {
+			exactLikes++
+		} else if k.id == c.ID && v.value == voteDislike {
+			exactDislikes++
+		}
+	}
+	value, ok := bloomVote(c.Likes-exactLikes, c.Dislikes-exactDislikes)
+	if !ok {
+		return 0, false, isso.ErrAlreadyVoted
+	}
+	return value, false, nil
+}
+
+// bloomVote return the vote of a voter only in the bloom filter, as database.Database does.
+func bloomVote(likes, dislikes int) (value int, ok bool) {
+	switch {
+	case likes <= 0 && dislikes <= 0:
+		return 0, true
+	case dislikes <= 0:
+		return voteLike, true
+	case likes <= 0:
+		return voteDislike, true
+	}
+	return 0, false
+}
+
+// UnvoteComment retract the like or dislike of voter from comment id.
+// It needs ExactVotes, isso.ErrNotVoted is returned if voter has no such vote.
+func (s *Storage) UnvoteComment(ctx context.Context, id int64, voter string, up bool) (int, int, error) {
+	if !s.ExactVotes {
+		return 0, 0, isso.ErrNotSupported
+	}
+	hashed, err := s.hashVoter(voter)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer s.lock()()
+	c, ok := s.data.comments[id]
+	if !ok {
+		return 0, 0, isso.ErrStorageNotFound
+	}
+	value := voteDislike
+	if up {
+		value = voteLike
+	}
+	key := voteKey{id, hashed}
+	v, voted := s.data.votes[key]
+	old := v.value
+	if bf := bloomfilter.RecoverFrom(c.Voters, c.Likes+c.Dislikes); !voted && bf.Contains([]byte(voter)) {
+		// a vote in the bloom filter only is migrated before it is retracted
+		if old, _, err = s.newVoter(c, voter, bf); err != nil {
+			return c.Likes, c.Dislikes, isso.ErrNotVoted
+		}
+		v.created = now()
+	}
+	if old != value {
+		return c.Likes, c.Dislikes, isso.ErrNotVoted
+	}
+	// the voter is kept without a vote, it is in the bloom filter
+	v.value = 0
+	s.data.votes[key] = v
+	addVote(&c.Comment, value, -1)
+	s.data.comments[id] = c
+	return c.Likes, c.Dislikes, nil
+}
+
+// ListVotes return exact votes of comments of thread, ordered by comment id and voter.
+func (s *Storage) ListVotes(ctx context.Context, threadID int64) ([]isso.Vote, error) {
+	defer s.lock()()
+	var votes []isso.Vote
+	for k, v := range s.data.votes {
+		if c, ok := s.data.comments[k.id]; ok && c.tid == threadID {
+			votes = append(votes, isso.Vote{CommentID: k.id, Voter: k.voter, Value: v.value, Created: v.created})
+		}
+	}
+	sort.Slice(votes, func(i, j int) bool {
+		if votes[i].CommentID != votes[j].CommentID {
+			return votes[i].CommentID < votes[j].CommentID
+		}
+		return votes[i].Voter < votes[j].Voter
+	})
+	return votes, nil
+}
+
+// RestoreVote save v as it is, the likes and dislikes of its comment are not changed.
+func (s *Storage) RestoreVote(ctx context.Context, v isso.Vote) error {
+	defer s.lock()()
+	key := voteKey{v.CommentID, v.Voter}
+	if _, ok := s.data.votes[key]; ok {
+		return isso.ErrInvalidParam
+	}
+	s.data.votes[key] = vote{v.Value, v.Created}
+	return nil
+}
+
+func addVote(c *isso.Comment, value int, n int) {
+	if value == voteLike {
+		c.Likes += n
+	} else {
+		c.Dislikes += n
+	}
+}
+
+// removeStaleVotes remove votes, reactions and rendered html of removed comments.
+func (s *Storage) removeStaleVotes() {
+	for k := range s.data.votes {
+		if _, ok := s.data.comments[k.id]; !ok {
+			delete(s.data.votes, k)
+		}
+	}
+	for k := range s.data.reactions {
+		if _, ok := s.data.comments[k.id]; !ok {
+			delete(s.data.reactions, k)
+		}
+	}
+	for id := range s.data.rendered {
+		if _, ok := s.data.comments[id]; !ok {
+			delete(s.data.rendered, id)
+		}
+	}
+}
@@ -13,15 +13,14 @@ import (
 	"github.com/rs/cors"
 	"github.com/sony/sonyflake"
 	"wrong.wang/x/go-isso/config"
-	"wrong.wang/x/go-isso/database"
 	"wrong.wang/x/go-isso/isso"
 	"wrong.wang/x/go-isso/logger"
 )
 
 // Serve starts a new HTTP server.
-func Serve(cfg config.Config) *http.Server {
+func Serve(cfg config.Config, storage isso.Storage) *http.Server {
 	server := &http.Server{
-		Handler:        setupHandler(cfg),
+		Handler:        setupHandler(cfg, storage),
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		IdleTimeout:    20 * time.Second,
@@ -69,7 +68,7 @@ func startHTTPServer(server *http.Server) {
 	}()
 }
 
-func setupHandler(cfg config.Config) http.Handler {
+func setupHandler(cfg config.Config, storage isso.Storage) http.Handler {
 	router := mux.NewRouter()
 	router = router.MatcherFunc(func(r *http.Request, rm *mux.RouteMatch) bool {
 		origin := isso.FindOrigin(r)
@@ -86,10 +85,6 @@ func setupHandler(cfg config.Config) http.Handler {
 		return false
 	}).Subrouter()
 
-	storage, err := database.New(cfg.DBPath, 1*time.Second)
-	if err != nil {
-		logger.Fatal("init database failed %w", err)
-	}
 	registerRoute(router, isso.New(cfg, storage))
 
 	c := cors.New(cors.Options{
@@ -0,0 +1,72 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// emojis are the supported shortcodes, a subset of the ones of GitHub.
+var emojis = map[string]string{
+	"+1": "👍", "-1": "👎", "thumbsup": "👍", "thumbsdown": "👎", "ok_hand": "👌", "clap": "👏",
+	"wave": "👋", "raised_hands": "🙌", "pray": "🙏", "muscle": "💪", "point_up": "☝️", "v": "✌️",
+	"smile": "😄", "smiley": "😃", "grin": "😁", "laughing": "😆", "joy": "😂", "rofl": "🤣",
+	"sweat_smile": "😅", "blush": "😊", "slightly_smiling_face": "🙂", "upside_down_face": "🙃",
+	"wink": "😉", "heart_eyes": "😍", "kissing_heart": "😘", "yum": "😋", "stuck_out_tongue": "😛",
+	"sunglasses": "😎", "nerd_face": "🤓", "thinking": "🤔", "neutral_face": "😐", "expressionless": "😑",
+	"unamused": "😒", "roll_eyes": "🙄", "grimacing": "😬", "relieved": "😌", "pensive": "😔",
+	"confused": "😕", "worried": "😟", "slightly_frowning_face": "🙁", "open_mouth": "😮",
+	"astonished": "😲", "flushed": "😳", "cry": "😢", "sob": "😭", "scream": "😱", "angry": "😠",
+	"rage": "😡", "sleeping": "😴", "mask": "😷", "innocent": "😇", "smirk": "😏", "hugs": "🤗",
+	"shrug": "🤷", "facepalm": "🤦", "eyes": "👀", "skull": "💀", "ghost": "👻", "robot": "🤖",
+	"see_no_evil": "🙈", "heart": "❤️", "broken_heart": "💔", "sparkling_heart": "💖", "star": "⭐",
+	"sparkles": "✨", "fire": "🔥", "zap": "⚡", "boom": "💥", "100": "💯", "tada": "🎉", "confetti_ball": "🎊",
+	"gift": "🎁", "trophy": "🏆", "medal_sports": "🏅", "rocket": "🚀", "bulb": "💡", "warning": "⚠️",
+	"x": "❌", "white_check_mark": "✅", "heavy_check_mark": "✔️", "question": "❓", "exclamation": "❗",
+	"bug": "🐛", "wrench": "🔧", "hammer": "🔨", "lock": "🔒", "key": "🔑", "memo": "📝", "book": "📖",
+	"link": "🔗", "email": "📧", "computer": "💻", "coffee": "☕", "beer": "🍺", "pizza": "🍕", "cake": "🍰",
+	"sunny": "☀️", "cloud": "☁️", "umbrella": "☔", "snowflake": "❄️", "rainbow": "🌈", "earth_asia": "🌏",
+	"cat": "🐱", "dog": "🐶", "penguin": "🐧", "snake": "🐍", "crab": "🦀", "turtle": "🐢", "unicorn": "🦄",
+	"seedling": "🌱", "rose": "🌹", "hourglass": "⌛", "alarm_clock": "⏰", "calendar": "📆", "chart_with_upwards_trend": "📈",
+}
+
+// emojiExtension replace :shortcode: by its emoji, unknown shortcodes are kept as they are.
+type emojiExtension struct{}
+
+// Extend implements goldmark.Extender.
+func (emojiExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(emojiParser{}, 999)))
+}
+
+type emojiParser struct{}
+
+// Trigger implements parser.InlineParser.
+func (emojiParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+// Parse implements parser.InlineParser.
+func (emojiParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	i := 1
+	for i < len(line) && isShortcode(line[i]) {
+		i++
+	}
+	if i == 1 || i >= len(line) || line[i] != ':' {
+		return nil
+	}
+	emoji, ok := emojis[string(line[1:i])]
+	if !ok {
+		return nil
+	}
+	block.Advance(i + 1)
+	return ast.NewString([]byte(emoji))
+}
+
+func isShortcode(c byte) bool {
+	return c == '+' || c == '-' || c == '_' || isDigit(c) || 'a' <= c && c <= 'z'
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
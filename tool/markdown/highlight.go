@@ -0,0 +1,132 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	highlighting "github.com/yuin/goldmark-highlighting"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// defaultHighlightMaxBytes is the size cap of a highlighted code block if none is configured.
+const defaultHighlightMaxBytes = 16 << 10
+
+// highlighter render fenced code blocks of allowed languages by goldmark-highlighting with chroma.
+// Tokens are wrapped in spans of chroma classes and the code element has the class chroma,
+// so a site styles them with the css of `chroma --html-styles`, e.g.
+//
+//	.chroma .k { color: #a626a4; }
+//
+// Other code blocks are rendered as the default renderer of goldmark does.
+type highlighter struct {
+	// languages are the names of allowed chroma lexers, nil allows all
+	languages map[string]bool
+	maxBytes  int
+	// chroma is the fenced code block renderer of goldmark-highlighting
+	chroma renderer.NodeRendererFunc
+}
+
+// newHighlighter return a highlighter of allowed languages, or of every language chroma knows
+// if none is allowed. Code blocks larger than maxBytes are not highlighted.
+func newHighlighter(allowed []string, maxBytes int) (*highlighter, error) {
+	h := &highlighter{maxBytes: maxBytes}
+	if h.maxBytes <= 0 {
+		h.maxBytes = defaultHighlightMaxBytes
+	}
+	for _, name := range allowed {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		lexer := lexers.Get(strings.TrimSpace(name))
+		if lexer == nil {
+			return nil, fmt.Errorf("highlight language %q is not supported", name)
+		}
+		if h.languages == nil {
+			h.languages = map[string]bool{}
+		}
+		h.languages[lexer.Config().Name] = true
+	}
+	highlighting.NewHTMLRenderer(
+		highlighting.WithFormatOptions(chromahtml.WithClasses(true), chromahtml.PreventSurroundingPre(true)),
+		highlighting.WithWrapperRenderer(wrapCode),
+	).RegisterFuncs(fencedCodeBlockFunc{&h.chroma})
+	return h, nil
+}
+
+// fencedCodeBlockFunc keep the function a renderer.NodeRenderer registers for fenced code blocks.
+type fencedCodeBlockFunc struct {
+	fn *renderer.NodeRendererFunc
+}
+
+// Register implements renderer.NodeRendererFuncRegisterer.
+func (f fencedCodeBlockFunc) Register(kind ast.NodeKind, fn renderer.NodeRendererFunc) {
+	if kind == ast.KindFencedCodeBlock {
+		*f.fn = fn
+	}
+}
+
+// wrapCode write the pre and code elements around highlighted code, the code element has
+// the class language-<info> as the default renderer of goldmark.
+func wrapCode(w util.BufWriter, c highlighting.CodeBlockContext, entering bool) {
+	if !entering {
+		_, _ = w.WriteString("</code></pre>\n")
+		return
+	}
+	_, _ = w.WriteString(`<pre><code class="chroma`)
+	if language, ok := c.Language(); ok {
+		_, _ = w.WriteString(" language-")
+		_, _ = w.Write(util.EscapeHTML(language))
+	}
+	_, _ = w.WriteString(`">`)
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (h *highlighter) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, h.renderFencedCodeBlock)
+}
+
+// renderFencedCodeBlock write the whole code block on entering, as goldmark-highlighting does.
+func (h *highlighter) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+	info := n.Language(source)
+	if h.allow(info, n) {
+		return h.chroma(w, source, node, entering)
+	}
+	_, _ = w.WriteString("<pre><code")
+	if info != nil {
+		_, _ = w.WriteString(` class="language-`)
+		_, _ = w.Write(util.EscapeHTML(info))
+		_ = w.WriteByte('"')
+	}
+	_ = w.WriteByte('>')
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		_, _ = w.Write(util.EscapeHTML(line.Value(source)))
+	}
+	_, _ = w.WriteString("</code></pre>\n")
+	return ast.WalkContinue, nil
+}
+
+// allow report whether the code block n of language info is highlighted.
+func (h *highlighter) allow(info []byte, n *ast.FencedCodeBlock) bool {
+	if info == nil {
+		return false
+	}
+	var size int
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		size += line.Len()
+	}
+	if size > h.maxBytes {
+		return false
+	}
+	lexer := lexers.Get(string(info))
+	return lexer != nil && (h.languages == nil || h.languages[lexer.Config().Name])
+}
@@ -0,0 +1,102 @@
+package markdown
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// linkRel is forced on every link of comments to other sites, search engines do not
+// follow them and the linked page gets no handle to the comment page.
+const linkRel = "nofollow ugc noopener"
+
+// LinkPolicy decide which links of comments and websites of authors are kept.
+// Only relative links and links with the schemes http, https and mailto are kept,
+// javascript:, data: and so on are always removed.
+type LinkPolicy struct {
+	// TargetBlank open links to other sites in a new tab.
+	TargetBlank bool
+	// AllowedDomains, if not empty, are the only domains links may point to,
+	// BlockedDomains are domains links must not point to. A domain matches its subdomains too.
+	AllowedDomains []string
+	BlockedDomains []string
+	// BareLinksAsText render bare links like <https://example.com> as text for unapproved authors.
+	BareLinksAsText bool
+}
+
+// AllowWebsite report whether website is an absolute http or https link allowed by the policy.
+func (p LinkPolicy) AllowWebsite(website string) bool {
+	if !safeURL(website) {
+		return false
+	}
+	u, err := url.Parse(strings.TrimSpace(website))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return (scheme == "http" || scheme == "https") && p.allowHost(u.Hostname())
+}
+
+// allowLink report whether link is not empty and has a safe scheme and an allowed domain,
+// and whether it is to another site.
+func (p LinkPolicy) allowLink(link string) (allowed bool, external bool) {
+	if strings.TrimSpace(link) == "" || !safeURL(link) {
+		return false, false
+	}
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return false, false
+	}
+	if u.Host == "" {
+		// relative or mailto
+		return true, false
+	}
+	return p.allowHost(u.Hostname()), true
+}
+
+func (p LinkPolicy) allowHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if matchDomain(host, p.BlockedDomains) {
+		return false
+	}
+	return len(normalizeDomains(p.AllowedDomains)) == 0 || matchDomain(host, p.AllowedDomains)
+}
+
+// matchDomain report whether host is one of domains or their subdomains.
+func matchDomain(host string, domains []string) bool {
+	for _, d := range normalizeDomains(domains) {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeDomains return domains lower-cased, without "*." prefixes and empty ones.
+func normalizeDomains(domains []string) []string {
+	var normalized []string
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		d = strings.TrimPrefix(strings.TrimPrefix(d, "*"), ".")
+		if d = strings.TrimSuffix(d, "."); d != "" {
+			normalized = append(normalized, d)
+		}
+	}
+	return normalized
+}
+
+// bareLinkText render bare links as their text.
+type bareLinkText struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (bareLinkText) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindAutoLink, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			_, _ = w.Write(util.EscapeHTML(node.(*ast.AutoLink).Label(source)))
+		}
+		return ast.WalkContinue, nil
+	})
+}
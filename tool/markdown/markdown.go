@@ -3,26 +3,151 @@ package markdown
 import (
 	"bytes"
 	"fmt"
+	"strings"
 
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
 )
 
 // Worker do markdown convert
 type Worker struct {
 	m goldmark.Markdown
+	// unapproved render comments of unapproved authors, it is m if the link policy
+	// treats them the same
+	unapproved goldmark.Markdown
+	sanitizer  *Sanitizer
 }
 
-// Convert markdown to html
+// Options configure a Worker.
+type Options struct {
+	// Extensions are names of goldmark extensions to enable: table, strikethrough,
+	// autolink, tasklist, footnote, typographer, hardwraps, emoji and mention.
+	Extensions []string
+	// AllowedElements and AllowedAttributes are allowed in the html
+	// besides DefaultElements, DefaultAttributes and what the extensions need.
+	AllowedElements   []string
+	AllowedAttributes []string
+	// Highlight fenced code blocks of HighlightLanguages, or of all languages chroma knows if it is empty,
+	// tokens are wrapped in spans of chroma classes like <span class="k">, see highlighter.
+	// Code blocks larger than HighlightMaxBytes (16 KiB if it is 0) are not highlighted.
+	Highlight          bool
+	HighlightLanguages []string
+	HighlightMaxBytes  int
+	// Links is the policy of links, rel="nofollow ugc noopener" is set on every link to another site.
+	Links LinkPolicy
+}
+
+// HasExtension report whether the extension name is enabled.
+func (o Options) HasExtension(name string) bool {
+	for _, ext := range o.Extensions {
+		if strings.EqualFold(strings.TrimSpace(ext), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// markdownExtension is a goldmark option and the html elements and attributes it renders besides the defaults.
+type markdownExtension struct {
+	option     goldmark.Option
+	elements   []string
+	attributes map[string][]string // allowed on their element only, see Sanitizer.allowOn
+}
+
+// extensions are the supported extensions by name.
+var extensions = map[string]markdownExtension{
+	"table":         {goldmark.WithExtensions(extension.Table), []string{"tr"}, nil},
+	"strikethrough": {goldmark.WithExtensions(extension.Strikethrough), nil, nil},
+	"autolink":      {goldmark.WithExtensions(extension.Linkify), nil, nil},
+	// tasklist render checkboxes, a type or checked on other elements is stripped
+	"tasklist": {goldmark.WithExtensions(extension.TaskList),
+		[]string{"input"}, map[string][]string{"input": {"type", "checked", "disabled"}}},
+	// footnote set ids like fn:1 on the list items and fnref:1 on the references
+	"footnote": {goldmark.WithExtensions(extension.Footnote),
+		[]string{"sup", "section"}, map[string][]string{"sup": {"id"}, "li": {"id"}}},
+	"typographer": {goldmark.WithExtensions(extension.Typographer), nil, nil},
+	// hardwraps render every newline in a paragraph as <br>
+	"hardwraps": {goldmark.WithRendererOptions(html.WithHardWraps()), nil, nil},
+	// emoji replace :shortcode: by its emoji, mention link @Author to a comment, see ConvertOptions
+	"emoji":   {goldmark.WithExtensions(emojiExtension{}), nil, nil},
+	"mention": {goldmark.WithExtensions(mentionExtension{}), nil, nil},
+}
+
+// Convert markdown to html, the html is sanitized, see Sanitizer.
 func (w *Worker) Convert(source string) (string, error) {
+	return w.convert(w.m, source)
+}
+
+// ConvertWith convert markdown to html as Convert, but bare links of unapproved authors
+// are text if the link policy says so, and mentions are resolved by opts.Mentions.
+func (w *Worker) ConvertWith(source string, opts ConvertOptions) (string, error) {
+	m := w.m
+	if opts.Unapproved {
+		m = w.unapproved
+	}
+	var parseOpts []parser.ParseOption
+	if len(opts.Mentions) > 0 {
+		pc := parser.NewContext()
+		pc.Set(mentionsKey, opts.Mentions)
+		parseOpts = append(parseOpts, parser.WithContext(pc))
+	}
+	return w.convert(m, source, parseOpts...)
+}
+
+func (w *Worker) convert(m goldmark.Markdown, source string, opts ...parser.ParseOption) (string, error) {
 	var buf bytes.Buffer
-	err := w.m.Convert([]byte(source), &buf)
+	err := m.Convert([]byte(source), &buf, opts...)
 	if err != nil {
 		return "", fmt.Errorf("markdown convert failed %w", err)
 	}
-	return buf.String(), nil
+	return w.sanitizer.Sanitize(buf.String()), nil
 }
 
-// New return a converter worker
-func New() *Worker {
-	return &Worker{goldmark.New()}
+// New return a converter worker, an error is returned for unknown extensions.
+func New(opts Options) (*Worker, error) {
+	var options []goldmark.Option
+	elements := append([]string{}, opts.AllowedElements...)
+	attributes := map[string][]string{}
+	for _, name := range opts.Extensions {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		ext, ok := extensions[name]
+		if !ok {
+			return nil, fmt.Errorf("markdown extension %q is not supported", name)
+		}
+		options = append(options, ext.option)
+		elements = append(elements, ext.elements...)
+		for element, attrs := range ext.attributes {
+			attributes[element] = append(attributes[element], attrs...)
+		}
+	}
+	if opts.Highlight {
+		h, err := newHighlighter(opts.HighlightLanguages, opts.HighlightMaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(h, 100))))
+		elements = append(elements, "span")
+	}
+	w := &Worker{m: goldmark.New(options...), sanitizer: NewSanitizer(elements, opts.AllowedAttributes)}
+	for element, attrs := range attributes {
+		w.sanitizer.allowOn(element, attrs...)
+	}
+	if opts.Highlight {
+		w.sanitizer.allowOn("span", "class")
+		w.sanitizer.allowOn("code", "class")
+	}
+	w.sanitizer.links = &opts.Links
+	w.unapproved = w.m
+	if opts.Links.BareLinksAsText {
+		options = append(options, goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(bareLinkText{}, 100))))
+		w.unapproved = goldmark.New(options...)
+	}
+	return w, nil
 }
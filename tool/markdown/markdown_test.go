@@ -0,0 +1,415 @@
+package markdown
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestWorker_Convert(t *testing.T) {
+	tests := []struct {
+		name   string
+		worker *Worker
+		source string
+		want   string
+	}{
+		{"markdown", mustNew(Options{}), "**bold** and [link](https://example.com)",
+			`<p><strong>bold</strong> and <a href="https://example.com" rel="nofollow ugc noopener">link</a></p>` + "\n"},
+		{"raw html omitted", mustNew(Options{}), "<b>bold</b>", "<p>bold</p>\n"},
+		{"javascript link", mustNew(Options{}), "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"image not allowed", mustNew(Options{}), "![alt](https://example.com/a.png)", "<p></p>\n"},
+		{"image allowed", mustNew(Options{AllowedElements: []string{"img"}, AllowedAttributes: []string{"src", " alt"}}), "![alt](https://example.com/a.png)",
+			`<p><img src="https://example.com/a.png" alt="alt"></p>` + "\n"},
+		{"code class stripped", mustNew(Options{}), "```go\na < b\n```", "<pre><code>a &lt; b\n</code></pre>\n"},
+		{"entities kept escaped", mustNew(Options{}), "a &lt;b&gt; & \"c\"", "<p>a &lt;b&gt; &amp; &#34;c&#34;</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.worker.Convert(tt.source)
+			if err != nil || got != tt.want {
+				t.Errorf("Worker.Convert() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorker_Convert_extensions(t *testing.T) {
+	tests := []struct {
+		extension string
+		source    string
+		want      string
+	}{
+		{"table", "| a | b |\n|:--|---|\n| 1 | 2 |", "<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th>b</th>\n" +
+			"</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"strikethrough", "~~gone~~", "<p><del>gone</del></p>\n"},
+		{"autolink", "see https://example.com",
+			`<p>see <a href="https://example.com" rel="nofollow ugc noopener">https://example.com</a></p>` + "\n"},
+		{"tasklist", "- [x] done", "<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n</ul>\n"},
+		{"footnote", "a[^1]\n\n[^1]: note", "<p>a<sup id=\"fnref:1\"><a href=\"#fn:1\">1</a></sup></p>\n" +
+			"<section>\n<hr>\n<ol>\n<li id=\"fn:1\">\n<p>note <a href=\"#fnref:1\">\u21a9\ufe0e</a></p>\n</li>\n</ol>\n</section>\n"},
+		{"typographer", `"quoted" -- text...`, "<p>“quoted” – text…</p>\n"},
+		{"hardwraps", "line\nbreak", "<p>line<br>\nbreak</p>\n"},
+		{"emoji", ":tada: at 10:30: :+1::unknown:", "<p>🎉 at 10:30: 👍:unknown:</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.extension, func(t *testing.T) {
+			got, err := mustNew(Options{Extensions: []string{tt.extension}}).Convert(tt.source)
+			if err != nil || got != tt.want {
+				t.Errorf("Worker.Convert() = %q, %v, want %q", got, err, tt.want)
+			}
+			// without the extension, nothing of it is rendered
+			if got, _ := mustNew(Options{}).Convert(tt.source); got == tt.want {
+				t.Errorf("Worker.Convert() without %s = %q", tt.extension, got)
+			}
+		})
+	}
+	if _, err := New(Options{Extensions: []string{"table", "mermaid"}}); err == nil {
+		t.Errorf("New() with unknown extension should fail")
+	}
+
+	// the attributes of an extension are allowed on its elements only
+	s := mustNew(Options{Extensions: []string{"footnote"}}).sanitizer
+	source := `<p id="x">a</p><h2 id="y">b</h2><sup id="fnref:1">1</sup>`
+	if got, want := s.Sanitize(source), `<p>a</p><h2>b</h2><sup id="fnref:1">1</sup>`; got != want {
+		t.Errorf("Sanitizer.Sanitize() of footnote = %q, want %q", got, want)
+	}
+	s = mustNew(Options{Extensions: []string{"tasklist"}, AllowedElements: []string{"div"}}).sanitizer
+	source = `<a href="/" type="x">a</a><div checked disabled>b</div><input checked="" disabled="" type="checkbox">`
+	if got, want := s.Sanitize(source), `<a href="/">a</a><div>b</div><input checked="" disabled="" type="checkbox">`; got != want {
+		t.Errorf("Sanitizer.Sanitize() of tasklist = %q, want %q", got, want)
+	}
+}
+
+func TestWorker_Convert_highlight(t *testing.T) {
+	w := mustNew(Options{Highlight: true, HighlightLanguages: []string{"go", "py", " sql"}, HighlightMaxBytes: 64})
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"go", "```go\nfunc f() string { return \"a<b\" } // nil\n```",
+			`<pre><code class="chroma language-go"><span class="kd">func</span> <span class="nf">f</span><span class="p">()</span> ` +
+				`<span class="kt">string</span> <span class="p">{</span> <span class="k">return</span> ` +
+				`<span class="s">&#34;a&lt;b&#34;</span> <span class="p">}</span> <span class="c1">// nil` + "\n</span></code></pre>\n"},
+		{"alias", "```python\nx = None  # 1\n```",
+			`<pre><code class="chroma language-python"><span class="n">x</span> <span class="o">=</span> ` +
+				`<span class="bp">None</span>  <span class="c1"># 1</span>` + "\n</code></pre>\n"},
+		{"ignore case", "```SQL\nSelect 1 -- x\n```",
+			`<pre><code class="chroma language-SQL"><span class="k">Select</span> <span class="mi">1</span> ` +
+				`<span class="c1">-- x` + "\n</span></code></pre>\n"},
+		{"unclosed string", "```go\ns := \"a\nb\n```",
+			`<pre><code class="chroma language-go"><span class="nx">s</span> <span class="o">:=</span> ` +
+				`<span class="err">&#34;</span><span class="nx">a</span>` + "\n" + `<span class="nx">b</span>` + "\n</code></pre>\n"},
+		{"not allowed", "```rust\nfn main() {}\n```", `<pre><code class="language-rust">fn main() {}` + "\n</code></pre>\n"},
+		{"unknown", "```brainfuck\n+[<]\n```", `<pre><code class="language-brainfuck">+[&lt;]` + "\n</code></pre>\n"},
+		{"no language", "```\nfunc\n```", "<pre><code>func\n</code></pre>\n"},
+		{"too large", "```go\n" + strings.Repeat("var x\n", 11) + "```",
+			`<pre><code class="language-go">` + strings.Repeat("var x\n", 11) + "</code></pre>\n"},
+		{"language escaped", "```\"><b>\nx\n```", `<pre><code class="language-&#34;&gt;&lt;b&gt;">x` + "\n</code></pre>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.Convert(tt.source)
+			if err != nil || got != tt.want {
+				t.Errorf("Worker.Convert() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+	if _, err := New(Options{Highlight: true, HighlightLanguages: []string{"go", "klingon"}}); err == nil {
+		t.Errorf("New() with unknown highlight language should fail")
+	}
+
+	// class is allowed on span and code only
+	w = mustNew(Options{Highlight: true, Extensions: []string{"footnote"}})
+	got, err := w.Convert("a[^1]\n\n[^1]: b")
+	if err != nil || strings.Contains(got, "class=") {
+		t.Errorf("Worker.Convert() of footnote = %q, %v, want no class", got, err)
+	}
+}
+
+func TestWorker_Convert_links(t *testing.T) {
+	w := mustNew(Options{Extensions: []string{"autolink"}, Links: LinkPolicy{
+		TargetBlank:     true,
+		AllowedDomains:  []string{"example.com", "*.example.org"},
+		BlockedDomains:  []string{"spam.example.com"},
+		BareLinksAsText: true,
+	}})
+	external := ` rel="nofollow ugc noopener" target="_blank"`
+	tests := []struct {
+		name       string
+		source     string
+		want       string
+		unapproved string
+	}{
+		{"allowed", "[a](https://example.com/x)", `<p><a href="https://example.com/x"` + external + ">a</a></p>\n", ""},
+		{"subdomain", "[a](http://www.Example.org)", `<p><a href="http://www.Example.org"` + external + ">a</a></p>\n", ""},
+		{"not allowed", "[a](https://example.net)", "<p>a</p>\n", ""},
+		{"lookalike", "[a](https://notexample.com)", "<p>a</p>\n", ""},
+		{"blocked", "[a](https://spam.example.com:8080/)", "<p>a</p>\n", ""},
+		{"protocol relative", "[a](//example.net/x)", "<p>a</p>\n", ""},
+		{"relative", "[a](/post/#isso-1)", `<p><a href="/post/#isso-1">a</a></p>` + "\n", ""},
+		{"mailto", "[a](mailto:a@example.net)", `<p><a href="mailto:a@example.net">a</a></p>` + "\n", ""},
+		{"data", "[a](data:text/html,x)", "<p>a</p>\n", ""},
+		{"bare link", "see <https://example.com>", `<p>see <a href="https://example.com"` + external + ">https://example.com</a></p>\n",
+			"<p>see https://example.com</p>\n"},
+		{"linkified", "see https://example.com", `<p>see <a href="https://example.com"` + external + ">https://example.com</a></p>\n",
+			"<p>see https://example.com</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.Convert(tt.source)
+			if err != nil || got != tt.want {
+				t.Errorf("Worker.Convert() = %q, %v, want %q", got, err, tt.want)
+			}
+			if tt.unapproved == "" {
+				tt.unapproved = tt.want
+			}
+			got, err = w.ConvertWith(tt.source, ConvertOptions{Unapproved: true})
+			if err != nil || got != tt.unapproved {
+				t.Errorf("Worker.ConvertWith() = %q, %v, want %q", got, err, tt.unapproved)
+			}
+		})
+	}
+}
+
+func TestWorker_ConvertWith_mentions(t *testing.T) {
+	w := mustNew(Options{Extensions: []string{"mention"}, Links: LinkPolicy{TargetBlank: true}})
+	mentions := map[string]int64{"Jane": 1, "Jane Doe": 2, "李雷": 3}
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"author", "@Jane thanks", `<p><a href="#isso-1">@Jane</a> thanks</p>` + "\n"},
+		{"longest", "@jane doe, @Jane!", `<p><a href="#isso-2">@jane doe</a>, <a href="#isso-1">@Jane</a>!</p>` + "\n"},
+		{"unicode", "@李雷：好", `<p><a href="#isso-3">@李雷</a>：好</p>` + "\n"},
+		{"prefix", "@Janet", "<p>@Janet</p>\n"},
+		{"unknown", "@John", "<p>@John</p>\n"},
+		{"email", "jane@Jane.example", "<p>jane@Jane.example</p>\n"},
+		{"code", "`@Jane`", "<p><code>@Jane</code></p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.ConvertWith(tt.source, ConvertOptions{Mentions: mentions})
+			if err != nil || got != tt.want {
+				t.Errorf("Worker.ConvertWith() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+	if got, _ := w.Convert("@Jane"); got != "<p>@Jane</p>\n" {
+		t.Errorf("Worker.Convert() without mentions = %q", got)
+	}
+}
+
+func TestLinkPolicy_AllowWebsite(t *testing.T) {
+	p := LinkPolicy{BlockedDomains: []string{"spam.example"}}
+	for website, want := range map[string]bool{
+		"https://example.com":        true,
+		"HTTP://example.com/me":      true,
+		"example.com":                false,
+		"/about/":                    false,
+		"javascript:alert(1)":        false,
+		"java\tscript://example.com": false,
+		"data:text/html,x":           false,
+		"ftp://example.com":          false,
+		"https://www.spam.example":   false,
+	} {
+		if got := p.AllowWebsite(website); got != want {
+			t.Errorf("LinkPolicy.AllowWebsite(%q) = %v, want %v", website, got, want)
+		}
+	}
+}
+
+func TestNewMarkup(t *testing.T) {
+	source := "# Title\n\n*em* `<code>` [link](https://example.com) ![alt](https://example.com/a.png)\n" +
+		"<b>html</b> https://example.com\n\n- item\n\n```\ncode\n```"
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"", "<h1>Title</h1>\n<p><em>em</em> <code>&lt;code&gt;</code> " +
+			`<a href="https://example.com" rel="nofollow ugc noopener">link</a> ` + "\nhtml https://example.com</p>\n" +
+			"<ul>\n<li>item</li>\n</ul>\n<pre><code>code\n</code></pre>\n"},
+		{"Restricted", "<p># Title</p>\n<p><em>em</em> <code>&lt;code&gt;</code> " +
+			`<a href="https://example.com" rel="nofollow ugc noopener">link</a> alt` + "\n&lt;b&gt;html&lt;/b&gt; https://example.com</p>\n" +
+			"<p>- item</p>\n<p><code>code</code></p>\n"},
+		{"plain", "<p># Title</p>\n<p>*em* `&lt;code&gt;` [link](https://example.com) ![alt](https://example.com/a.png)<br>\n" +
+			"&lt;b&gt;html&lt;/b&gt; https://example.com</p>\n<p>- item</p>\n<p>```<br>\ncode<br>\n```</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			m, err := NewMarkup(tt.language, Options{})
+			if err != nil {
+				t.Fatalf("NewMarkup() error = %v", err)
+			}
+			if got, err := m.Convert(source); err != nil || got != tt.want {
+				t.Errorf("Markup.Convert() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	restricted, _ := NewMarkup(LanguageRestricted, Options{Links: LinkPolicy{BareLinksAsText: true}})
+	if got, err := restricted.ConvertWith("see <https://example.com>", ConvertOptions{Unapproved: true}); err != nil || got != "<p>see https://example.com</p>\n" {
+		t.Errorf("Markup.ConvertWith() = %q, %v", got, err)
+	}
+	plain, _ := NewMarkup(LanguagePlain, Options{})
+	if got, err := plain.Convert("a\r\nb\r\n \r\n\r\nc\n"); err != nil || got != "<p>a<br>\nb</p>\n<p>c</p>\n" {
+		t.Errorf("Markup.Convert() = %q, %v", got, err)
+	}
+	if _, err := NewMarkup("rst", Options{}); err == nil {
+		t.Errorf("NewMarkup() with unknown language should fail")
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	opts := Options{Extensions: []string{"table"}}
+	if Renderer("", opts) != Renderer(" Markdown", Options{Extensions: []string{"table"}}) {
+		t.Errorf("Renderer() of the same markup differs")
+	}
+	for _, other := range []string{
+		Renderer(LanguagePlain, opts),
+		Renderer("", Options{Extensions: []string{"table", "footnote"}}),
+		Renderer("", Options{Extensions: []string{"table"}, Links: LinkPolicy{TargetBlank: true}}),
+	} {
+		if other == Renderer("", opts) {
+			t.Errorf("Renderer() of another markup = %s", other)
+		}
+	}
+}
+
+func TestSanitizer_Sanitize(t *testing.T) {
+	s := NewSanitizer([]string{"img", "Script", "div"}, []string{"src", "onerror", "title"})
+	styled := NewSanitizer([]string{"span"}, []string{"style"})
+	for fragment, want := range map[string]string{
+		`<span style="color: red">x</span>`:                           `<span style="color: red">x</span>`,
+		`<span style="background: url(https://example.com)">x</span>`: `<span>x</span>`,
+		`<span style="width: expression(alert(1))">x</span>`:          `<span>x</span>`,
+		`<span style="color: \72 ed">x</span>`:                        `<span>x</span>`,
+	} {
+		if got := styled.Sanitize(fragment); got != want {
+			t.Errorf("Sanitizer.Sanitize(%q) = %q, want %q", fragment, got, want)
+		}
+	}
+	tests := []struct {
+		fragment string
+		want     string
+	}{
+		{`<p align="center" class="x">text</p>`, `<p align="center">text</p>`},
+		{`<div title="a &quot;b&quot;">x</div>`, `<div title="a &#34;b&#34;">x</div>`},
+		{`<span>kept text</span>`, `kept text`},
+		{`<script>alert(1)</script>after`, `after`},
+		{`<img src="x" onerror="alert(1)" />`, `<img src="x" />`},
+		{`<img src="java&#x09;script:alert(1)">`, `<img>`},
+		{`<a href="mailto:a@example.com">mail</a>`, `<a href="mailto:a@example.com">mail</a>`},
+		{`<a href="/relative?a=1&amp;b=2">r</a>`, `<a href="/relative?a=1&amp;b=2">r</a>`},
+		{`<!-- comment --><p>x</p>`, `<p>x</p>`},
+		{`<iframe src="https://example.com">inner</iframe>x`, `x`},
+		{`<div style="color: red">x</div>`, `<div>x</div>`},
+	}
+	for _, tt := range tests {
+		if got := s.Sanitize(tt.fragment); got != tt.want {
+			t.Errorf("Sanitizer.Sanitize(%q) = %q, want %q", tt.fragment, got, tt.want)
+		}
+	}
+}
+
+func mustNew(opts Options) *Worker {
+	w, err := New(opts)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+// TestWorker_Convert_xss render every vector of testdata/xss.txt, the output must only have
+// allowed elements and attributes, and links with safe schemes.
+func TestWorker_Convert_xss(t *testing.T) {
+	f, err := os.Open("testdata/xss.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	elements := []string{"img", "div", "span"}
+	attributes := []string{"src", "title", "alt", "style", "background", "class", "rel"}
+	var all []string
+	for name, ext := range extensions {
+		all = append(all, name)
+		elements = append(elements, ext.elements...)
+		for _, attrs := range ext.attributes {
+			attributes = append(attributes, attrs...)
+		}
+	}
+	allowed := NewSanitizer(elements, attributes)
+	markups := map[string]Markup{
+		"default":   mustNew(Options{}),
+		"extra":     mustNew(Options{Extensions: all, AllowedElements: elements, AllowedAttributes: attributes}),
+		"highlight": mustNew(Options{Highlight: true}),
+	}
+	for _, language := range []string{LanguageRestricted, LanguagePlain} {
+		m, err := NewMarkup(language, Options{Links: LinkPolicy{BareLinksAsText: true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		markups[language] = m
+	}
+
+	scanner := bufio.NewScanner(f)
+	var n int
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		n++
+		vector := strings.ReplaceAll(line, `\n`, "\n")
+		if got := allowed.Sanitize(vector); unsafeHTML(allowed, got) != "" {
+			t.Errorf("Sanitizer.Sanitize(%q) = %q, %s", vector, got, unsafeHTML(allowed, got))
+		}
+		for name, m := range markups {
+			got, err := m.Convert(vector)
+			if err != nil {
+				t.Errorf("%s: Worker.Convert(%q) error = %v", name, vector, err)
+				continue
+			}
+			if reason := unsafeHTML(allowed, got); reason != "" {
+				t.Errorf("%s: Worker.Convert(%q) = %q, %s", name, vector, got, reason)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%d vectors", n)
+}
+
+// unsafeHTML return why fragment is unsafe, or an empty string.
+func unsafeHTML(s *Sanitizer, fragment string) string {
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.CommentToken, html.DoctypeToken:
+			return "has comment or doctype"
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			token := z.Token()
+			if !s.elements[token.Data] {
+				return "has element " + token.Data
+			}
+			for _, attr := range token.Attr {
+				if !s.allowAttr(token.Data, attr) {
+					return "has attribute " + attr.Key + "=" + attr.Val
+				}
+				lower := strings.ToLower(attr.Val)
+				if strings.Contains(lower, "javascript:") || strings.Contains(lower, "vbscript:") ||
+					(urlAttributes[attr.Key] && strings.HasPrefix(lower, "data:")) {
+					return "has script in " + attr.Key
+				}
+			}
+		}
+	}
+}
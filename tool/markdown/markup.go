@@ -0,0 +1,143 @@
+package markdown
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Markup render the text of comments to sanitized html.
+type Markup interface {
+	Convert(source string) (string, error)
+	// ConvertWith render the text of a comment as Convert, but as opts describe it.
+	ConvertWith(source string, opts ConvertOptions) (string, error)
+}
+
+// ConvertOptions describe the comment a text is rendered for.
+type ConvertOptions struct {
+	// Unapproved means the author is not approved, see LinkPolicy.BareLinksAsText.
+	Unapproved bool
+	// Mentions are the ids of comments of authors in the same thread, @Author is rendered
+	// as a link to its comment if the mention extension is enabled.
+	Mentions map[string]int64
+}
+
+// Markup languages, see NewMarkup.
+const (
+	// LanguageMarkdown is Markdown with the configured extensions, see Worker.
+	LanguageMarkdown = "markdown"
+	// LanguageRestricted is inline Markdown only: emphasis, code and links in paragraphs.
+	LanguageRestricted = "restricted"
+	// LanguagePlain is text without formatting, paragraphs are separated by blank lines.
+	LanguagePlain = "plain"
+)
+
+// Version is increased whenever html rendered from the same text with the same options changes,
+// e.g. goldmark is upgraded, so html saved by an older version is rendered again.
+const Version = 1
+
+// Renderer identify the Markup of language and opts, html rendered by Markups with the same
+// Renderer is the same.
+func Renderer(language string, opts Options) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		language = LanguageMarkdown
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%+v", opts)))
+	return fmt.Sprintf("%s-v%d-%x", language, Version, sum[:6])
+}
+
+// restrictedElements and restrictedAttributes are all the restricted language renders,
+// the allowed elements and attributes of Options do not apply to it.
+var (
+	restrictedElements   = []string{"a", "br", "code", "em", "p", "strong"}
+	restrictedAttributes = []string{"href"}
+)
+
+// NewMarkup return the Markup of language, the empty language is LanguageMarkdown.
+// Options other than Links apply to LanguageMarkdown only.
+func NewMarkup(language string, opts Options) (Markup, error) {
+	switch strings.ToLower(strings.TrimSpace(language)) {
+	case "", LanguageMarkdown:
+		return New(opts)
+	case LanguageRestricted:
+		return newRestricted(opts.Links), nil
+	case LanguagePlain:
+		return plainText{}, nil
+	}
+	return nil, fmt.Errorf("markup language %q is not supported", language)
+}
+
+// newRestricted return a Worker parsing paragraphs, emphasis, code spans and links only,
+// images are rendered as their alt text.
+func newRestricted(links LinkPolicy) *Worker {
+	p := parser.NewParser(
+		parser.WithBlockParsers(util.Prioritized(parser.NewParagraphParser(), 1000)),
+		parser.WithInlineParsers(
+			util.Prioritized(parser.NewCodeSpanParser(), 100),
+			util.Prioritized(parser.NewLinkParser(), 200),
+			util.Prioritized(parser.NewAutoLinkParser(), 300),
+			util.Prioritized(parser.NewEmphasisParser(), 500),
+		),
+		parser.WithParagraphTransformers(parser.DefaultParagraphTransformers()...),
+	)
+	options := []goldmark.Option{
+		goldmark.WithParser(p),
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(imageText{}, 100))),
+	}
+	w := &Worker{m: goldmark.New(options...), sanitizer: newSanitizer(restrictedElements, restrictedAttributes)}
+	w.sanitizer.links = &links
+	w.unapproved = w.m
+	if links.BareLinksAsText {
+		options = append(options, goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(bareLinkText{}, 100))))
+		w.unapproved = goldmark.New(options...)
+	}
+	return w
+}
+
+// imageText render images as their alt text.
+type imageText struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.
+func (imageText) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindImage, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		// the children are the alt text
+		return ast.WalkContinue, nil
+	})
+}
+
+// plainText render text as it is, html is escaped, paragraphs are separated by blank lines
+// and other newlines are line breaks.
+type plainText struct{}
+
+// Convert implements Markup.
+func (plainText) Convert(source string) (string, error) {
+	var b strings.Builder
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+			paragraph = paragraph[:0]
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		paragraph = append(paragraph, string(util.EscapeHTML([]byte(line))))
+	}
+	flush()
+	return b.String(), nil
+}
+
+// ConvertWith implements Markup, there are no links in plain text.
+func (t plainText) ConvertWith(source string, opts ConvertOptions) (string, error) {
+	return t.Convert(source)
+}
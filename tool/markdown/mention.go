@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// mentionsKey is the parser context key of the mentions of ConvertOptions.
+var mentionsKey = parser.NewContextKey()
+
+// mentionExtension render @Author as a link to the comment #isso-<id> of Author in
+// ConvertOptions.Mentions, other @names are kept as text.
+type mentionExtension struct{}
+
+// Extend implements goldmark.Extender.
+func (mentionExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(util.Prioritized(mentionParser{}, 999)))
+}
+
+type mentionParser struct{}
+
+// Trigger implements parser.InlineParser.
+func (mentionParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+// Parse implements parser.InlineParser. Authors are matched case-insensitively, the longest
+// one wins, so @Jane Doe is not @Jane if both are mentioned.
+func (mentionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	mentions, _ := pc.Get(mentionsKey).(map[string]int64)
+	if len(mentions) == 0 || isMentionRune(block.PrecendingCharacter()) {
+		// e.g. the @ of an email address
+		return nil
+	}
+	line, segment := block.PeekLine()
+	var name string
+	var id int64
+	for author, commentID := range mentions {
+		n := 1 + len(author)
+		if author == "" || n > len(line) || !strings.EqualFold(string(line[1:n]), author) {
+			continue
+		}
+		if next, _ := utf8.DecodeRune(line[n:]); n < len(line) && isMentionRune(next) {
+			continue
+		}
+		if len(author) > len(name) || len(author) == len(name) && author < name {
+			name, id = author, commentID
+		}
+	}
+	if name == "" {
+		return nil
+	}
+	n := 1 + len(name)
+	link := ast.NewLink()
+	link.Destination = []byte(fmt.Sprintf("#isso-%d", id))
+	link.AppendChild(link, ast.NewTextSegment(segment.WithStop(segment.Start+n)))
+	block.Advance(n)
+	return link
+}
+
+func isMentionRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
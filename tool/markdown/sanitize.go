@@ -0,0 +1,230 @@
+package markdown
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultElements are the HTML elements always allowed in rendered comments, as documented in [markup].
+var DefaultElements = []string{
+	"a", "blockquote", "br", "code", "del", "em", "h1", "h2", "h3", "h4", "h5", "h6", "hr",
+	"ins", "li", "ol", "p", "pre", "strong", "table", "tbody", "td", "th", "thead", "ul",
+}
+
+// DefaultAttributes are the HTML attributes always allowed, on every allowed element.
+var DefaultAttributes = []string{"align", "href"}
+
+// dropContent are elements removed together with their content, they can not be allowed.
+// Other disallowed elements are removed but keep their text.
+var dropContent = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "template": true,
+	"noscript": true, "noembed": true, "noframes": true, "textarea": true, "title": true, "xmp": true,
+	"svg": true, "math": true, "select": true,
+}
+
+// urlAttributes hold links, they are allowed only with a safe scheme.
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true, "formaction": true,
+	"poster": true, "background": true, "longdesc": true, "srcset": true, "xlink:href": true,
+}
+
+// safeSchemes of links, a link without scheme is relative and safe.
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// Sanitizer remove HTML elements and attributes not in its allow-lists.
+// Event handler attributes like onclick are never allowed.
+type Sanitizer struct {
+	elements   map[string]bool
+	attributes map[string]bool
+	// elementAttributes are allowed on one element only, see allowOn
+	elementAttributes map[string]map[string]bool
+	// links, if set, is applied to every <a>, see sanitizeLink
+	links *LinkPolicy
+}
+
+// NewSanitizer return a Sanitizer allowing the default elements and attributes plus extra ones,
+// names are case insensitive.
+func NewSanitizer(elements []string, attributes []string) *Sanitizer {
+	return newSanitizer(append(append([]string{}, DefaultElements...), elements...),
+		append(append([]string{}, DefaultAttributes...), attributes...))
+}
+
+// newSanitizer return a Sanitizer allowing only elements and attributes.
+func newSanitizer(elements []string, attributes []string) *Sanitizer {
+	s := &Sanitizer{elements: map[string]bool{}, attributes: map[string]bool{},
+		elementAttributes: map[string]map[string]bool{}}
+	for _, e := range elements {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" && !dropContent[e] {
+			s.elements[e] = true
+		}
+	}
+	for _, a := range attributes {
+		if a = strings.ToLower(strings.TrimSpace(a)); a != "" && !strings.HasPrefix(a, "on") {
+			s.attributes[a] = true
+		}
+	}
+	return s
+}
+
+// allowOn allow attributes on element only, e.g. the classes of highlighted code.
+func (s *Sanitizer) allowOn(element string, attributes ...string) {
+	if s.elementAttributes[element] == nil {
+		s.elementAttributes[element] = map[string]bool{}
+	}
+	for _, a := range attributes {
+		if a = strings.ToLower(strings.TrimSpace(a)); a != "" && !strings.HasPrefix(a, "on") {
+			s.elementAttributes[element][a] = true
+		}
+	}
+}
+
+// Sanitize return fragment with disallowed elements, attributes and unsafe links removed.
+// Text and attribute values are escaped again, comments and doctypes are dropped.
+func (s *Sanitizer) Sanitize(fragment string) string {
+	var buf bytes.Buffer
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	// dropping > 0 means inside elements removed with their content
+	dropping := 0
+	// anchors is whether each open <a> is kept
+	var anchors []bool
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			// io.EOF, the tokenizer fails on nothing else reading a string
+			return buf.String()
+		}
+		token := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if dropContent[token.Data] {
+				if tt == html.StartTagToken {
+					dropping++
+				}
+				continue
+			}
+			if dropping > 0 || !s.elements[token.Data] {
+				continue
+			}
+			attrs, extra := token.Attr, ""
+			if token.Data == "a" && s.links != nil {
+				var keep bool
+				attrs, extra, keep = s.sanitizeLink(attrs)
+				if tt == html.StartTagToken {
+					anchors = append(anchors, keep)
+				}
+				if !keep {
+					continue
+				}
+			}
+			buf.WriteString("<" + token.Data)
+			for _, attr := range attrs {
+				if s.allowAttr(token.Data, attr) {
+					buf.WriteString(" " + attr.Key + `="` + html.EscapeString(attr.Val) + `"`)
+				}
+			}
+			buf.WriteString(extra)
+			if tt == html.SelfClosingTagToken {
+				buf.WriteString(" /")
+			}
+			buf.WriteString(">")
+		case html.EndTagToken:
+			if dropContent[token.Data] {
+				if dropping > 0 {
+					dropping--
+				}
+				continue
+			}
+			if dropping > 0 || !s.elements[token.Data] {
+				continue
+			}
+			if token.Data == "a" && s.links != nil && len(anchors) > 0 {
+				keep := anchors[len(anchors)-1]
+				anchors = anchors[:len(anchors)-1]
+				if !keep {
+					continue
+				}
+			}
+			buf.WriteString("</" + token.Data + ">")
+		case html.TextToken:
+			if dropping == 0 {
+				buf.WriteString(html.EscapeString(token.Data))
+			}
+		}
+	}
+}
+
+// sanitizeLink return the attributes of an <a> without rel and target, the rel and target
+// attributes set by the link policy for links to other sites instead, and whether to keep the <a>.
+// A link without href or to a disallowed domain is removed but keeps its text.
+func (s *Sanitizer) sanitizeLink(attrs []html.Attribute) ([]html.Attribute, string, bool) {
+	var sanitized []html.Attribute
+	var hasHref, external bool
+	for _, attr := range attrs {
+		switch strings.ToLower(attr.Key) {
+		case "rel", "target":
+			continue
+		case "href":
+			var allowed bool
+			if allowed, external = s.links.allowLink(attr.Val); !allowed {
+				return nil, "", false
+			}
+			hasHref = true
+		}
+		sanitized = append(sanitized, attr)
+	}
+	if !hasHref {
+		return nil, "", false
+	}
+	var extra string
+	if external {
+		extra = ` rel="` + linkRel + `"`
+		if s.links.TargetBlank {
+			extra += ` target="_blank"`
+		}
+	}
+	return sanitized, extra, true
+}
+
+func (s *Sanitizer) allowAttr(element string, attr html.Attribute) bool {
+	key := strings.ToLower(attr.Key)
+	if attr.Namespace != "" || !s.attributes[key] && !s.elementAttributes[element][key] || strings.HasPrefix(key, "on") {
+		return false
+	}
+	switch {
+	case urlAttributes[key]:
+		return safeURL(attr.Val)
+	case key == "style":
+		return safeStyle(attr.Val)
+	}
+	return true
+}
+
+// safeStyle report whether css loads nothing and runs no script, escapes of css are refused too.
+func safeStyle(css string) bool {
+	css = strings.ToLower(css)
+	for _, unsafe := range []string{"url(", "expression(", "javascript:", "@import", `\`} {
+		if strings.Contains(css, unsafe) {
+			return false
+		}
+	}
+	return true
+}
+
+// safeURL report whether link is relative or has a safe scheme.
+func safeURL(link string) bool {
+	// browsers ignore control characters and spaces in schemes, like "java\tscript:"
+	link = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, link)
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "" || safeSchemes[strings.ToLower(u.Scheme)]
+}